@@ -0,0 +1,443 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// AIGLiteral is a literal of an and-inverter graph in AIGER's numbering: twice
+// the variable, plus one if the literal is negated. Variable zero is the
+// constant false, so AIGLiteral 0 is false and 1 is true.
+type AIGLiteral uint32
+
+// AIGLatch is a latch, or one bit of state, of an and-inverter graph. At each
+// step, the latch takes the value that Next had at the previous step. Reset is
+// the latch's initial value: 0 or 1 for a constant, or Literal for an
+// uninitialized latch that may start with either value.
+type AIGLatch struct {
+	Literal, Next, Reset AIGLiteral
+}
+
+// AIGAnd is the and gate LHS = RHS0 && RHS1 of an and-inverter graph.
+type AIGAnd struct {
+	LHS, RHS0, RHS1 AIGLiteral
+}
+
+// AIG is an and-inverter graph, a sequential circuit of inputs, latches, and
+// and gates, as described by an AIGER file. Inputs, latches' Literals, and
+// and gates' LHSs are unnegated literals of distinct variables at most
+// MaxVariable. See http://fmv.jku.at/aiger/ for the format.
+type AIG struct {
+	MaxVariable int
+	Inputs      []AIGLiteral
+	Latches     []AIGLatch
+	Outputs     []AIGLiteral
+	// Bad lists the bad-state properties, which BMC tries to make true. If Bad
+	// is empty, BMC uses Outputs instead, as AIGER 1.0 files do.
+	Bad []AIGLiteral
+	// Constraints lists invariant constraints, which must be true in every step
+	// of a trace.
+	Constraints []AIGLiteral
+	Ands        []AIGAnd
+}
+
+// ReadAIGER reads an and-inverter graph in the ASCII ("aag") or binary ("aig")
+// AIGER format from r. ReadAIGER supports the bad-state and invariant
+// constraint sections of AIGER 1.9, but returns an error if the input has
+// justice or fairness properties. It ignores the symbol table and comments.
+// ReadAIGER returns an error if the input is malformed, if a literal exceeds
+// the header's maximum variable, or if a variable is defined more than once or
+// used without being defined.
+// It does not check for cyclic and gates.
+func ReadAIGER(r io.Reader) (*AIG, error) {
+	br := bufio.NewReader(r)
+	line := 1
+	text, err := readAIGERLine(br)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %v", line, err)
+	}
+	fields := strings.Fields(text)
+	if len(fields) < 6 || len(fields) > 10 ||
+		fields[0] != "aag" && fields[0] != "aig" {
+		return nil, fmt.Errorf("line 1: malformed header %q, expected "+
+			"\"aag M I L O A [B C J F]\" or \"aig M I L O A [B C J F]\"", text)
+	}
+	binary := fields[0] == "aig"
+	var counts [9]int // M I L O A B C J F
+	for i, field := range fields[1:] {
+		if counts[i], err = strconv.Atoi(field); err != nil || counts[i] < 0 {
+			return nil, fmt.Errorf("line 1: invalid count %q", field)
+		}
+	}
+	m, i, l, o, a := counts[0], counts[1], counts[2], counts[3], counts[4]
+	if counts[7] != 0 || counts[8] != 0 {
+		return nil, fmt.Errorf("line 1: justice and fairness properties not supported")
+	}
+	if m >= 1<<31-1 || binary && m != i+l+a || m < i+l+a {
+		return nil, fmt.Errorf("line 1: inconsistent counts in header %q", text)
+	}
+	g := &AIG{MaxVariable: m}
+	// A binary file defines its variables implicitly, each exactly once. An
+	// ASCII file's M need not be I+L+A, so defined is a map, which costs
+	// memory only for the definitions the input actually has.
+	defined := make(map[AIGLiteral]bool)
+	maxLit := AIGLiteral(2*m + 1)
+
+	// next reads the next line's literals, requiring exactly n of them, or n-1
+	// or n if optional is true.
+	next := func(n int, optional bool) ([]AIGLiteral, error) {
+		line++
+		text, err := readAIGERLine(br)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		fields := strings.Fields(text)
+		if len(fields) != n && !(optional && len(fields) == n-1) {
+			return nil, fmt.Errorf("line %d: expected %d literals, got %q", line, n, text)
+		}
+		lits := make([]AIGLiteral, len(fields))
+		for j, field := range fields {
+			lit, err := strconv.ParseUint(field, 10, 32)
+			if err != nil || AIGLiteral(lit) > maxLit {
+				return nil, fmt.Errorf("line %d: invalid literal %q", line, field)
+			}
+			lits[j] = AIGLiteral(lit)
+		}
+		return lits, nil
+	}
+	define := func(lit AIGLiteral) error {
+		if lit < 2 || lit&1 != 0 || defined[lit>>1] {
+			return fmt.Errorf("line %d: cannot define literal %d", line, lit)
+		}
+		if !binary {
+			defined[lit>>1] = true
+		}
+		return nil
+	}
+
+	for k := 0; k < i; k++ {
+		lit := AIGLiteral(2 * (k + 1))
+		if !binary {
+			lits, err := next(1, false)
+			if err != nil {
+				return nil, err
+			}
+			lit = lits[0]
+		}
+		if err := define(lit); err != nil {
+			return nil, err
+		}
+		g.Inputs = append(g.Inputs, lit)
+	}
+	for k := 0; k < l; k++ {
+		var latch AIGLatch
+		if binary {
+			lits, err := next(2, true)
+			if err != nil {
+				return nil, err
+			}
+			latch.Literal = AIGLiteral(2 * (i + k + 1))
+			latch.Next = lits[0]
+			if len(lits) == 2 {
+				latch.Reset = lits[1]
+			}
+		} else {
+			lits, err := next(3, true)
+			if err != nil {
+				return nil, err
+			}
+			latch.Literal, latch.Next = lits[0], lits[1]
+			if len(lits) == 3 {
+				latch.Reset = lits[2]
+			}
+		}
+		if err := define(latch.Literal); err != nil {
+			return nil, err
+		}
+		if latch.Reset > 1 && latch.Reset != latch.Literal {
+			return nil, fmt.Errorf("line %d: invalid reset value %d", line, latch.Reset)
+		}
+		g.Latches = append(g.Latches, latch)
+	}
+	for _, section := range []struct {
+		n    int
+		dest *[]AIGLiteral
+	}{{o, &g.Outputs}, {counts[5], &g.Bad}, {counts[6], &g.Constraints}} {
+		for k := 0; k < section.n; k++ {
+			lits, err := next(1, false)
+			if err != nil {
+				return nil, err
+			}
+			*section.dest = append(*section.dest, lits[0])
+		}
+	}
+	for k := 0; k < a; k++ {
+		var and AIGAnd
+		if binary {
+			and.LHS = AIGLiteral(2 * (i + l + k + 1))
+			delta0, err := readAIGERDelta(br)
+			if err == nil && delta0 > uint64(and.LHS) {
+				err = fmt.Errorf("invalid delta %d", delta0)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("and gate %d: %v", k, err)
+			}
+			and.RHS0 = and.LHS - AIGLiteral(delta0)
+			delta1, err := readAIGERDelta(br)
+			if err == nil && delta1 > uint64(and.RHS0) {
+				err = fmt.Errorf("invalid delta %d", delta1)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("and gate %d: %v", k, err)
+			}
+			and.RHS1 = and.RHS0 - AIGLiteral(delta1)
+		} else {
+			lits, err := next(3, false)
+			if err != nil {
+				return nil, err
+			}
+			and = AIGAnd{lits[0], lits[1], lits[2]}
+		}
+		if err := define(and.LHS); err != nil {
+			return nil, err
+		}
+		g.Ands = append(g.Ands, and)
+	}
+
+	uses := append(append(append([]AIGLiteral(nil), g.Outputs...), g.Bad...),
+		g.Constraints...)
+	for _, latch := range g.Latches {
+		uses = append(uses, latch.Next)
+	}
+	for _, and := range g.Ands {
+		uses = append(uses, and.RHS0, and.RHS1)
+	}
+	for _, lit := range uses {
+		if lit > 1 && !binary && !defined[lit>>1] {
+			return nil, fmt.Errorf("literal %d is used but not defined", lit)
+		}
+	}
+	return g, nil
+}
+
+// readAIGERLine reads one line of an AIGER file, without its newline.
+func readAIGERLine(br *bufio.Reader) (string, error) {
+	text, err := br.ReadString('\n')
+	if err == io.EOF && text != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return strings.TrimSuffix(text, "\n"), err
+}
+
+// readAIGERDelta reads one of the variable-length integers encoding the binary
+// AIGER format's and gates: seven bits per byte, least significant first, with
+// the high bit set on every byte but the last.
+func readAIGERDelta(br *bufio.Reader) (uint64, error) {
+	var x uint64
+	for shift := uint(0); ; shift += 7 {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return 0, err
+		}
+		if shift > 28 {
+			return 0, fmt.Errorf("delta overflows")
+		}
+		x |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return x, nil
+		}
+	}
+}
+
+// AIGFrame maps the variables of an AIG to Pigosat literals, indexed by AIG
+// variable. Element zero must be a literal that is false in every solution.
+type AIGFrame []Literal
+
+// Literal returns the Pigosat literal corresponding to lit.
+func (f AIGFrame) Literal(lit AIGLiteral) Literal {
+	if lit&1 != 0 {
+		return -f[lit>>1]
+	}
+	return f[lit>>1]
+}
+
+// Encode sets frame's elements for g's and gates to new Pigosat variables
+// numbered from *next, which Encode advances, and returns the Tseitin encoding
+// of the gates: clauses satisfied exactly when each gate's variable equals the
+// conjunction of its inputs. frame must have length g.MaxVariable+1, and the
+// caller must set its elements for g's inputs and latches beforehand.
+func (g *AIG) Encode(frame AIGFrame, next *Literal) Formula {
+	for _, and := range g.Ands {
+		frame[and.LHS>>1] = *next
+		*next++
+	}
+	formula := make(Formula, 0, 3*len(g.Ands))
+	for _, and := range g.Ands {
+		x := frame.Literal(and.LHS)
+		a, b := frame.Literal(and.RHS0), frame.Literal(and.RHS1)
+		formula = append(formula, Clause{-x, a}, Clause{-x, b}, Clause{x, -a, -b})
+	}
+	return formula
+}
+
+// AIGTrace is a sequence of steps of an AIG leading to a bad state.
+type AIGTrace struct {
+	// Latches holds the latches' initial values, in the order of AIG.Latches.
+	Latches []bool
+	// Inputs holds the inputs' values at each step, in the order of
+	// AIG.Inputs. The last step reaches a bad state.
+	Inputs [][]bool
+}
+
+// BMC performs bounded model checking: it checks whether g can reach a bad
+// state, one in which any of g.Bad (or g.Outputs if g.Bad is empty) is true,
+// within k steps of a reset state while satisfying g.Constraints at every
+// step. If so, BMC returns the shortest such trace and status Satisfiable. If
+// not, BMC returns status Unsatisfiable. If a limit in options stops the
+// search, BMC returns status Unknown. BMC returns an error if New does.
+//
+// BMC unrolls g's transition relation one step at a time into a single Pigosat
+// instance, using a fresh copy of the Tseitin encoding of g's gates for each
+// step. For each step it assumes, with Assume, that a bad state is reached at
+// that step, so the clauses it learns carry over to the next step. If
+// g.MaxVariable exceeds the number of variables g defines, BMC first renumbers
+// them consecutively so that each step's frame is only as large as g.
+func (g *AIG) BMC(k int, options *Options) (trace *AIGTrace, status Status, err error) {
+	p, err := New(options)
+	if err != nil {
+		return nil, Unknown, err
+	}
+	defer p.Delete()
+	g = g.compact()
+	bad := g.Bad
+	if len(bad) == 0 {
+		bad = g.Outputs
+	}
+
+	next := Literal(2)
+	p.Add(Formula{{1}}) // Variable 1 is the constant true.
+	var frames []AIGFrame
+	for step := 0; step <= k; step++ {
+		frame := make(AIGFrame, g.MaxVariable+1)
+		frame[0] = -1
+		for _, in := range g.Inputs {
+			frame[in>>1] = next
+			next++
+		}
+		var clauses Formula
+		for _, latch := range g.Latches {
+			if step > 0 {
+				frame[latch.Literal>>1] = frames[step-1].Literal(latch.Next)
+				continue
+			}
+			frame[latch.Literal>>1] = next
+			switch latch.Reset {
+			case 0:
+				clauses = append(clauses, Clause{-next})
+			case 1:
+				clauses = append(clauses, Clause{next})
+			}
+			next++
+		}
+		clauses = append(clauses, g.Encode(frame, &next)...)
+		for _, c := range g.Constraints {
+			clauses = append(clauses, Clause{frame.Literal(c)})
+		}
+		// Reaching a bad state at this step implies some bad literal is true.
+		reached := next
+		next++
+		clause := Clause{-reached}
+		for _, b := range bad {
+			clause = append(clause, frame.Literal(b))
+		}
+		p.Add(append(clauses, clause))
+		frames = append(frames, frame)
+
+		p.Assume(reached)
+		solution, status := p.Solve()
+		if status == Unknown {
+			return nil, Unknown, nil
+		}
+		if status == Satisfiable {
+			return g.trace(frames, solution), Satisfiable, nil
+		}
+	}
+	return nil, Unsatisfiable, nil
+}
+
+// compact returns g if its variables are numbered 1 through g.MaxVariable, as
+// in a binary AIGER file. Otherwise it returns a copy of g whose inputs',
+// latches', and and gates' variables are renumbered consecutively in that
+// order, which preserves the order of Inputs and Latches that traces use.
+func (g *AIG) compact() *AIG {
+	n := len(g.Inputs) + len(g.Latches) + len(g.Ands)
+	if g.MaxVariable <= n {
+		return g
+	}
+	vars := make(map[AIGLiteral]AIGLiteral, n)
+	for _, in := range g.Inputs {
+		vars[in>>1] = AIGLiteral(len(vars) + 1)
+	}
+	for _, latch := range g.Latches {
+		vars[latch.Literal>>1] = AIGLiteral(len(vars) + 1)
+	}
+	for _, and := range g.Ands {
+		vars[and.LHS>>1] = AIGLiteral(len(vars) + 1)
+	}
+	lit := func(lit AIGLiteral) AIGLiteral {
+		if lit < 2 {
+			return lit
+		}
+		return vars[lit>>1]<<1 | lit&1
+	}
+	lits := func(src []AIGLiteral) []AIGLiteral {
+		var dest []AIGLiteral
+		for _, l := range src {
+			dest = append(dest, lit(l))
+		}
+		return dest
+	}
+	c := &AIG{MaxVariable: n, Inputs: lits(g.Inputs), Outputs: lits(g.Outputs),
+		Bad: lits(g.Bad), Constraints: lits(g.Constraints)}
+	for _, latch := range g.Latches {
+		c.Latches = append(c.Latches,
+			AIGLatch{lit(latch.Literal), lit(latch.Next), lit(latch.Reset)})
+	}
+	for _, and := range g.Ands {
+		c.Ands = append(c.Ands, AIGAnd{lit(and.LHS), lit(and.RHS0), lit(and.RHS1)})
+	}
+	return c
+}
+
+// trace extracts the values of g's initial latches and inputs from solution.
+func (g *AIG) trace(frames []AIGFrame, solution Solution) *AIGTrace {
+	value := func(lit Literal) bool {
+		if lit < 0 {
+			return !solution[-lit]
+		}
+		return solution[lit]
+	}
+	t := &AIGTrace{Latches: make([]bool, len(g.Latches))}
+	for i, latch := range g.Latches {
+		t.Latches[i] = value(frames[0].Literal(latch.Literal))
+	}
+	for _, frame := range frames {
+		inputs := make([]bool, len(g.Inputs))
+		for i, in := range g.Inputs {
+			inputs[i] = value(frame.Literal(in))
+		}
+		t.Inputs = append(t.Inputs, inputs)
+	}
+	return t
+}
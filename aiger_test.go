@@ -0,0 +1,220 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// counterAAG is a two-bit counter that increments when its input is true. Its
+// bad state is the count three.
+const counterAAG = `aag 11 1 2 0 8 1
+2
+4 13
+6 21
+22
+8 4 3
+10 5 2
+12 11 9
+14 4 2
+16 15 6
+18 14 7
+20 19 17
+22 6 4
+i0 enable
+c
+A comment
+`
+
+// counterAIG returns counterAAG in the binary AIGER format.
+func counterAIG() []byte {
+	b := bytes.NewBufferString("aig 11 1 2 0 8 1\n13\n21\n22\n")
+	for _, and := range [][3]uint{{8, 4, 3}, {10, 5, 2}, {12, 11, 9}, {14, 4, 2},
+		{16, 15, 6}, {18, 14, 7}, {20, 19, 17}, {22, 6, 4}} {
+		for _, delta := range []uint{and[0] - and[1], and[1] - and[2]} {
+			for ; delta >= 0x80; delta >>= 7 {
+				b.WriteByte(byte(delta&0x7f | 0x80))
+			}
+			b.WriteByte(byte(delta))
+		}
+	}
+	b.WriteString("i0 enable\n")
+	return b.Bytes()
+}
+
+func TestReadAIGER(t *testing.T) {
+	ascii, err := ReadAIGER(strings.NewReader(counterAAG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &AIG{
+		MaxVariable: 11,
+		Inputs:      []AIGLiteral{2},
+		Latches:     []AIGLatch{{4, 13, 0}, {6, 21, 0}},
+		Bad:         []AIGLiteral{22},
+		Ands: []AIGAnd{{8, 4, 3}, {10, 5, 2}, {12, 11, 9}, {14, 4, 2},
+			{16, 15, 6}, {18, 14, 7}, {20, 19, 17}, {22, 6, 4}},
+	}
+	if !reflect.DeepEqual(ascii, expected) {
+		t.Errorf("ASCII: expected %+v, got %+v", expected, ascii)
+	}
+	binary, err := ReadAIGER(bytes.NewReader(counterAIG()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(binary, expected) {
+		t.Errorf("Binary: expected %+v, got %+v", expected, binary)
+	}
+
+	good := []struct {
+		aiger    string
+		expected *AIG
+	}{
+		{"aag 0 0 0 0 0\n", &AIG{}},
+		{"aag 0 0 0 1 0\n1", &AIG{Outputs: []AIGLiteral{1}}},
+		{"aag 3 1 1 0 0 0 1\n2\n6 3 6\n7\n",
+			&AIG{MaxVariable: 3, Inputs: []AIGLiteral{2},
+				Latches: []AIGLatch{{6, 3, 6}}, Constraints: []AIGLiteral{7}}},
+		{"aig 2 1 1 0 0\n5 1\n",
+			&AIG{MaxVariable: 2, Inputs: []AIGLiteral{2},
+				Latches: []AIGLatch{{4, 5, 1}}}},
+		{"aag 2147483646 1 0 1 0\n4294967292\n4294967293\n",
+			&AIG{MaxVariable: 2147483646, Inputs: []AIGLiteral{4294967292},
+				Outputs: []AIGLiteral{4294967293}}},
+	}
+	for i, at := range good {
+		g, err := ReadAIGER(strings.NewReader(at.aiger))
+		if err != nil {
+			t.Errorf("good[%d]: %v", i, err)
+		} else if !reflect.DeepEqual(g, at.expected) {
+			t.Errorf("good[%d]: expected %+v, got %+v", i, at.expected, g)
+		}
+	}
+
+	bad := []string{
+		"",
+		"aag 1 1 0 0\n",
+		"aig 1 1 0 0 0 0 0 0 0 0\n",
+		"agg 1 1 0 0 0\n",
+		"aag 1 x 0 0 0\n",
+		"aag 1 1 0 0 0 0 0 1 0\n",
+		"aag 1 1 0 0 1\n2\n2 2 2\n",
+		"aig 2 1 0 0 0\n",
+		"aag 1 1 0 0 0\n",
+		"aag 1 1 0 0 0\n3\n",
+		"aag 1 1 0 0 0\n4\n",
+		"aag 1 1 0 0 0\n1\n",
+		"aag 2 2 0 0 0\n2\n2\n",
+		"aag 1 1 0 0 0\n2 2\n",
+		"aag 2 1 1 0 0\n2\n4 2 2\n",
+		"aag 1 0 0 1 0\n2\n",
+		"aag 2 0 0 0 1\n2 4 4\n",
+		"aig 1 0 0 0 1\n",
+		"aig 1 0 0 0 1\n\x03\x00",
+		"aig 1 0 0 0 1\n\x80\x80\x80\x80\x80\x01\x00",
+		"aag 2147483646 1 0 0 1\n2\n",
+		"aig 2147483646 1 0 0 0\n",
+		"aag 2147483647 0 0 0 0\n",
+	}
+	for i, aiger := range bad {
+		if _, err := ReadAIGER(strings.NewReader(aiger)); err == nil {
+			t.Errorf("bad[%d]: expected an error reading %q", i, aiger)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	// Check every input combination of x = a && !b against the encoding.
+	g := &AIG{MaxVariable: 3, Inputs: []AIGLiteral{2, 4}, Ands: []AIGAnd{{6, 2, 5}}}
+	frame := AIGFrame{-1, 2, 3, 0}
+	next := Literal(4)
+	formula := append(g.Encode(frame, &next), Clause{1})
+	if next != 5 || frame[3] != 4 {
+		t.Fatalf("Expected next=5 and frame[3]=4, got %d, %v", next, frame)
+	}
+	for _, a := range []bool{false, true} {
+		for _, b := range []bool{false, true} {
+			p, _ := New(nil)
+			p.Add(formula)
+			for lit, value := range map[Literal]bool{frame[1]: a, frame[2]: b} {
+				if !value {
+					lit = -lit
+				}
+				p.Assume(lit)
+			}
+			solution, status := p.Solve()
+			if status != Satisfiable || solution[4] != (a && !b) {
+				t.Errorf("a=%t, b=%t: got %v, %v", a, b, status, solution)
+			}
+			p.Delete()
+		}
+	}
+	if frame.Literal(7) != -4 || frame.Literal(0) != -1 || frame.Literal(1) != 1 {
+		t.Errorf("Literal is wrong for frame %v", frame)
+	}
+}
+
+func TestBMC(t *testing.T) {
+	g, err := ReadAIGER(strings.NewReader(counterAAG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := 0; k < 3; k++ {
+		if trace, status, err := g.BMC(k, nil); trace != nil || status != Unsatisfiable || err != nil {
+			t.Errorf("k=%d: expected nil, Unsatisfiable, nil; got %v, %v, %v", k,
+				trace, status, err)
+		}
+	}
+	trace, status, err := g.BMC(10, nil)
+	expected := &AIGTrace{Latches: []bool{false, false},
+		Inputs: [][]bool{{true}, {true}, {true}, nil}}
+	if status != Satisfiable || err != nil || trace == nil || len(trace.Inputs) != 4 {
+		t.Fatalf("Expected a trace of four steps, got %v, %v, %v", trace, status, err)
+	}
+	trace.Inputs[3] = nil // The last input does not matter.
+	if !reflect.DeepEqual(trace, expected) {
+		t.Errorf("Expected %v, got %v", expected, trace)
+	}
+
+	// An invariant constraint disabling the counter makes three unreachable.
+	g.Constraints = []AIGLiteral{3}
+	if _, status, _ := g.BMC(10, nil); status != Unsatisfiable {
+		t.Errorf("With a constraint, expected Unsatisfiable, got %v", status)
+	}
+
+	// Uninitialized latches may start in a bad state. Without Bad, BMC checks
+	// Outputs.
+	g, _ = ReadAIGER(strings.NewReader("aag 1 0 1 1 0\n2 2 2\n2\n"))
+	trace, status, _ = g.BMC(0, nil)
+	if status != Satisfiable || !reflect.DeepEqual(trace,
+		&AIGTrace{Latches: []bool{true}, Inputs: [][]bool{{}}}) {
+		t.Errorf("Uninitialized latch: got %v, %v", trace, status)
+	}
+	g.Latches[0].Reset = 1
+	g.Outputs[0] = 3
+	if _, status, _ = g.BMC(5, nil); status != Unsatisfiable {
+		t.Errorf("Latch reset to one: expected Unsatisfiable, got %v", status)
+	}
+
+	// A huge header maximum variable does not make the frames huge.
+	g, _ = ReadAIGER(strings.NewReader(
+		"aag 2147483646 1 1 1 0\n4294967292\n2 4294967293 0\n2\n"))
+	trace, status, _ = g.BMC(1, nil)
+	if status != Satisfiable || len(trace.Inputs) != 2 || trace.Inputs[0][0] {
+		t.Errorf("Huge maximum variable: got %v, %v", trace, status)
+	}
+}
+
+func ExampleAIG_BMC() {
+	g, err := ReadAIGER(strings.NewReader(counterAAG))
+	if err != nil {
+		panic(err)
+	}
+	trace, status, _ := g.BMC(10, nil)
+	fmt.Println(status, len(trace.Inputs)-1)
+	// Output: Satisfiable 3
+}
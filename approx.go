@@ -0,0 +1,256 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ApproxCountModels estimates CountModels(formula, projection) for formulas too
+// large to count exactly. With probability at least 1-delta, the estimate is
+// within a factor of 1+epsilon of the true count. epsilon must be positive and
+// delta must be between zero and one. rnd supplies the randomness. If rnd is
+// nil, ApproxCountModels seeds its own source from the current time.
+//
+// ApproxCountModels implements ApproxMC: Chakraborty, Meel, and Vardi, "A
+// Scalable Approximate Model Counter," CP 2013. It repeatedly adds random XOR
+// constraints over the projected variables, which split the solutions into
+// cells of roughly equal size, until a cell is small enough to enumerate with
+// Pigosat. The XOR constraints are encoded into CNF with auxiliary variables.
+func ApproxCountModels(formula Formula, projection []Literal, epsilon, delta float64,
+	rnd *rand.Rand) *big.Int {
+	if !(epsilon > 0) || !(delta > 0 && delta < 1) {
+		panic(fmt.Errorf("epsilon=%v must be positive and delta=%v must be in (0, 1)",
+			epsilon, delta))
+	}
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	h := newHashedFormula(formula, projection)
+	return h.approxCount(epsilon, delta, rnd)
+}
+
+// Sampler draws solutions of a formula nearly uniformly at random. A Sampler
+// is not safe for concurrent use.
+//
+// Sampler implements UniGen: Chakraborty, Meel, and Vardi, "Balancing
+// Scalability and Uniformity in SAT Witness Generator," DAC 2014. Like
+// ApproxCountModels, it uses random XOR constraints to pick a small cell of
+// solutions, and then picks a solution from the cell uniformly at random.
+type Sampler struct {
+	h      *hashedFormula
+	rnd    *rand.Rand
+	lo, hi int         // Bounds on acceptable cell sizes
+	q      int         // Largest number of XOR constraints to try
+	small  bool        // Whether there are few enough solutions to list
+	all    [][]Literal // All the solutions, if small is true
+}
+
+// NewSampler returns a Sampler for the solutions of formula projected onto the
+// variables in projection. See CountModels for how projection is interpreted.
+// The probability that Sample returns any given solution is within a factor of
+// 1+epsilon of the uniform probability. epsilon must be greater than 6.84.
+// Smaller values of epsilon make Sample slower. rnd supplies the randomness. If
+// rnd is nil, NewSampler seeds its own source from the current time.
+//
+// NewSampler estimates the number of solutions with ApproxCountModels, so it
+// may take much longer than a call to Sample.
+func NewSampler(formula Formula, projection []Literal, epsilon float64,
+	rnd *rand.Rand) *Sampler {
+	if !(epsilon > 6.84) {
+		panic(fmt.Errorf("epsilon=%v must be greater than 6.84", epsilon))
+	}
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	s := &Sampler{h: newHashedFormula(formula, projection), rnd: rnd}
+
+	// Solve epsilon = (1+kappa)(7.44 + 0.392/(1-kappa)^2) - 1 for kappa in
+	// (0, 1) by bisection. The right-hand side is increasing in kappa.
+	lo, hi := 0.0, 1.0
+	for i := 0; i < 64; i++ {
+		kappa := (lo + hi) / 2
+		if (1+kappa)*(7.44+0.392/((1-kappa)*(1-kappa)))-1 < epsilon {
+			lo = kappa
+		} else {
+			hi = kappa
+		}
+	}
+	kappa := lo
+	pivot := math.Ceil(4.03 * (1 + 1/kappa) * (1 + 1/kappa))
+	s.hi = int(1 + (1+kappa)*pivot)
+	s.lo = int(math.Ceil(pivot / (1 + kappa)))
+
+	if cubes := s.h.cell(nil, s.hi+1); len(cubes) <= s.hi {
+		s.small, s.all = true, cubes
+		return s
+	}
+	count := s.h.approxCount(0.8, 0.2, rnd)
+	log2 := float64(count.BitLen())
+	if count.BitLen() < 64 {
+		log2 = math.Log2(float64(count.Int64()))
+	}
+	s.q = int(math.Ceil(log2 + math.Log2(1.8) - math.Log2(pivot)))
+	return s
+}
+
+// Sample returns the literals over the projected variables that are true in a
+// randomly chosen solution, ordered by variable. Sample may fail with small
+// probability, in which case ok is false and you may call Sample again. If the
+// formula is unsatisfiable, Sample always fails.
+func (s *Sampler) Sample() (cube []Literal, ok bool) {
+	if s.small {
+		if len(s.all) == 0 {
+			return nil, false
+		}
+		return s.all[s.rnd.Intn(len(s.all))], true
+	}
+	for m := s.q - 3; m <= s.q; m++ {
+		if m < 0 {
+			continue
+		}
+		cubes := s.h.cell(s.h.randomXORs(s.rnd, m), s.hi+1)
+		if len(cubes) >= s.lo && len(cubes) <= s.hi {
+			return cubes[s.rnd.Intn(len(cubes))], true
+		}
+	}
+	return nil, false
+}
+
+// xorConstraint requires that an odd number of its variables are true if
+// parity is true, or an even number if parity is false.
+type xorConstraint struct {
+	vars   []Literal
+	parity bool
+}
+
+// hashedFormula is a formula whose solutions can be restricted to a cell of a
+// random hash function built from XOR constraints.
+type hashedFormula struct {
+	formula Formula
+	vars    []Literal // Projected variables in increasing order
+	next    Literal   // First variable available for XOR encodings
+}
+
+// newHashedFormula returns formula projected onto projection. See CountModels.
+func newHashedFormula(formula Formula, projection []Literal) *hashedFormula {
+	_, maxVar, _ := normalizeFormula(formula)
+	h := &hashedFormula{formula: formula}
+	seen := make(map[Literal]bool)
+	if projection == nil {
+		for v := Literal(1); v <= maxVar; v++ {
+			projection = append(projection, v)
+		}
+	}
+	for _, lit := range projection {
+		if v := variableOf(lit); v != 0 && !seen[v] {
+			seen[v] = true
+			h.vars = append(h.vars, v)
+		}
+	}
+	sort.Slice(h.vars, func(i, j int) bool { return h.vars[i] < h.vars[j] })
+	h.next = maxVar + 1
+	if len(h.vars) > 0 && h.vars[len(h.vars)-1] >= h.next {
+		h.next = h.vars[len(h.vars)-1] + 1
+	}
+	return h
+}
+
+// randomXORs returns m XOR constraints, each containing each projected
+// variable with probability one half and having a random parity.
+func (h *hashedFormula) randomXORs(rnd *rand.Rand, m int) []xorConstraint {
+	xors := make([]xorConstraint, m)
+	for i := range xors {
+		for _, v := range h.vars {
+			if rnd.Intn(2) == 1 {
+				xors[i].vars = append(xors[i].vars, v)
+			}
+		}
+		xors[i].parity = rnd.Intn(2) == 1
+	}
+	return xors
+}
+
+// cell returns up to limit solutions of h's formula and xors, as cubes over
+// the projected variables.
+func (h *hashedFormula) cell(xors []xorConstraint, limit int) [][]Literal {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(h.formula)
+	next := h.next
+	for _, x := range xors {
+		p.Add(x.clauses(&next))
+	}
+	var cubes [][]Literal
+	p.Enumerate(context.Background(), h.vars, limit, func(cube []Literal) bool {
+		cubes = append(cubes, cube)
+		return true
+	})
+	return cubes
+}
+
+// approxCount is the body of ApproxCountModels.
+func (h *hashedFormula) approxCount(epsilon, delta float64, rnd *rand.Rand) *big.Int {
+	threshold := 1 + int(math.Ceil(9.84*(1+epsilon/(1+epsilon))*
+		(1+1/epsilon)*(1+1/epsilon)))
+	if cubes := h.cell(nil, threshold); len(cubes) < threshold {
+		return big.NewInt(int64(len(cubes)))
+	}
+	iterations := int(math.Ceil(17 * math.Log2(3/delta)))
+	var estimates []*big.Int
+	for i := 0; i < iterations; i++ {
+		// The cells shrink as m grows because each hash is a prefix of the
+		// next, so binary search for the smallest m giving a small cell.
+		xors := h.randomXORs(rnd, len(h.vars))
+		sizes := make(map[int]int)
+		m := sort.Search(len(h.vars)+1, func(m int) bool {
+			sizes[m] = len(h.cell(xors[:m], threshold))
+			return sizes[m] < threshold
+		})
+		if m > len(h.vars) {
+			continue
+		}
+		size := sizes[m]
+		estimates = append(estimates,
+			new(big.Int).Lsh(big.NewInt(int64(size)), uint(m)))
+	}
+	if len(estimates) == 0 {
+		return new(big.Int)
+	}
+	sort.Slice(estimates, func(i, j int) bool {
+		return estimates[i].Cmp(estimates[j]) < 0
+	})
+	return estimates[len(estimates)/2]
+}
+
+// clauses encodes x in CNF by chaining two-input XORs through auxiliary
+// variables numbered from *next, which clauses advances.
+func (x xorConstraint) clauses(next *Literal) Formula {
+	if len(x.vars) == 0 {
+		if x.parity {
+			return Formula{{}}
+		}
+		return nil
+	}
+	var formula Formula
+	acc := x.vars[0]
+	for _, v := range x.vars[1:] {
+		// y <-> acc XOR v
+		y := *next
+		*next++
+		formula = append(formula,
+			Clause{-y, acc, v}, Clause{-y, -acc, -v},
+			Clause{y, -acc, v}, Clause{y, acc, -v})
+		acc = y
+	}
+	if x.parity {
+		return append(formula, Clause{acc})
+	}
+	return append(formula, Clause{-acc})
+}
@@ -0,0 +1,148 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// independentClauses returns n clauses (2i-1 OR 2i), which have 3^n models.
+func independentClauses(n int) Formula {
+	formula := make(Formula, n)
+	for i := range formula {
+		formula[i] = Clause{Literal(2*i + 1), Literal(2*i + 2)}
+	}
+	return formula
+}
+
+func TestXORClauses(t *testing.T) {
+	for n := 0; n <= 4; n++ {
+		for _, parity := range []bool{false, true} {
+			x := xorConstraint{parity: parity}
+			for v := 1; v <= n; v++ {
+				x.vars = append(x.vars, Literal(v))
+			}
+			next := Literal(n + 1)
+			formula := x.clauses(&next)
+			// Exactly half the assignments to n > 0 variables have each parity.
+			expected := int64(0)
+			if n > 0 {
+				expected = 1 << uint(n-1)
+			} else if !parity {
+				expected = 1
+			}
+			projection := x.vars
+			if projection == nil {
+				projection = []Literal{}
+			}
+			if c := CountModels(formula, projection); c.Cmp(big.NewInt(expected)) != 0 {
+				t.Errorf("n=%d, parity=%v: expected %d models, got %v", n,
+					parity, expected, c)
+			}
+		}
+	}
+}
+
+func TestApproxCountModels(t *testing.T) {
+	const epsilon, delta = 0.8, 0.2
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []int{2, 6} {
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			formula := independentClauses(n)
+			exact := CountModels(formula, nil)
+			approx := ApproxCountModels(formula, nil, epsilon, delta, rnd)
+			// Check exact/(1+epsilon) <= approx <= exact*(1+epsilon)
+			e, a := new(big.Float).SetInt(exact), new(big.Float).SetInt(approx)
+			lo := new(big.Float).Quo(e, big.NewFloat(1+epsilon))
+			hi := new(big.Float).Mul(e, big.NewFloat(1+epsilon))
+			if a.Cmp(lo) < 0 || a.Cmp(hi) > 0 {
+				t.Errorf("Expected about %v models, got %v", exact, approx)
+			}
+		})
+	}
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		if c := ApproxCountModels(Formula{{1}, {-1}}, nil, epsilon, delta, rnd); c.Sign() != 0 {
+			t.Errorf("Expected 0 models, got %v", c)
+		}
+	})
+
+	t.Run("bad parameters", func(t *testing.T) {
+		assertPanics(t, "ApproxCountModels", func() {
+			ApproxCountModels(Formula{{1}}, nil, 0, delta, rnd)
+		})
+		assertPanics(t, "ApproxCountModels", func() {
+			ApproxCountModels(Formula{{1}}, nil, epsilon, 1, rnd)
+		})
+	})
+}
+
+func TestSampler(t *testing.T) {
+	const epsilon = 16
+	rnd := rand.New(rand.NewSource(1))
+
+	// With few solutions, Sampler samples exactly uniformly.
+	t.Run("small", func(t *testing.T) {
+		const samples = 3000
+		s := NewSampler(Formula{{1, 2}}, nil, epsilon, rnd)
+		counts := make(map[string]int)
+		for i := 0; i < samples; i++ {
+			cube, ok := s.Sample()
+			if !ok {
+				t.Fatal("Sample failed")
+			}
+			counts[fmt.Sprint(cube)]++
+		}
+		if len(counts) != 3 {
+			t.Errorf("Expected 3 distinct samples, got %v", counts)
+		}
+		for cube, count := range counts {
+			if count < samples/3*8/10 || count > samples/3*12/10 {
+				t.Errorf("Sampled %s %d times out of %d", cube, count, samples)
+			}
+		}
+	})
+
+	t.Run("large", func(t *testing.T) {
+		const samples = 40
+		formula := independentClauses(5)
+		s := NewSampler(formula, nil, epsilon, rnd)
+		seen := make(map[string]bool)
+		failures := 0
+		for i := 0; i < samples; i++ {
+			cube, ok := s.Sample()
+			if !ok {
+				failures++
+				continue
+			}
+			solution := make(Solution, 11)
+			for _, lit := range cube {
+				solution[abs(lit)] = lit > 0
+			}
+			if len(cube) != 10 || !evaluate(formula, solution) {
+				t.Errorf("Sample %v is not a solution", cube)
+			}
+			seen[fmt.Sprint(cube)] = true
+		}
+		if failures > samples/2 {
+			t.Errorf("%d of %d samples failed", failures, samples)
+		}
+		if len(seen) < (samples-failures)/2 {
+			t.Errorf("Only %d distinct samples out of %d", len(seen),
+				samples-failures)
+		}
+	})
+
+	t.Run("unsatisfiable", func(t *testing.T) {
+		if _, ok := NewSampler(Formula{{1}, {-1}}, nil, epsilon, rnd).Sample(); ok {
+			t.Errorf("Sample succeeded on an unsatisfiable formula")
+		}
+	})
+
+	t.Run("bad epsilon", func(t *testing.T) {
+		assertPanics(t, "NewSampler", func() { NewSampler(nil, nil, 6, rnd) })
+	})
+}
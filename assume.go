@@ -4,7 +4,10 @@ package pigosat
 
 // #include "picosat.h"
 import "C"
-import "unsafe"
+import (
+	"math"
+	"unsafe"
+)
 
 // For testing litArrayToSlice in TestlitArrayToSlice since you can't import CGo
 // into test files.
@@ -16,11 +19,42 @@ var cZero C.int
 // literal you pass as an argument. An assumption remains valid after the next
 // call to Solve returns until a call to Add, Assume, or a second Solve. You can
 // add arbitrary many assumptions before the next call to Solve. Methods
-// FailedAssumptions, FailedAssumptions, MaxSatisfiableAssumptions, and
-// NextMaxSatisfiableAssumptions operate on the current, valid assumptions.
+// FailedAssumption, FailedAssumptions, MinUnsatisfiableAssumptions,
+// MaxSatisfiableAssumptions, and NextMaxSatisfiableAssumptions operate on the
+// current, valid assumptions.
+//
+// Assume panics with a *LiteralError if lit is zero, math.MinInt32, or its
+// variable exceeds Options.MaxVariable. Use AssumeChecked to get an error
+// instead.
 func (p *Pigosat) Assume(lit Literal) {
 	defer p.ready(false)()
-	p.couldHaveFailedAssumptions = false
+	if err := checkLiteral(lit, p.maxVariable); err != nil {
+		panic(err)
+	}
+	p.assume(lit)
+}
+
+// AssumeChecked is like Assume, but returns a *LiteralError instead of
+// panicking if lit is invalid, and returns ErrDeleted instead of panicking if p
+// is uninitialized or deleted. If AssumeChecked returns an error, it does not
+// add the assumption.
+func (p *Pigosat) AssumeChecked(lit Literal) error {
+	unlock, err := p.readyChecked(false)
+	defer unlock()
+	if err != nil {
+		return err
+	}
+	if err = checkLiteral(lit, p.maxVariable); err != nil {
+		return err
+	}
+	p.assume(lit)
+	return nil
+}
+
+// assume is the body of Assume and AssumeChecked.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) assume(lit Literal) {
+	p.state = stateReady
 	// void picosat_assume (PicoSAT *, int lit);
 	C.picosat_assume(p.p, C.int(lit))
 }
@@ -33,8 +67,8 @@ func (p *Pigosat) Assume(lit Literal) {
 // unsatisfiability.
 func (p *Pigosat) FailedAssumption(lit Literal) bool {
 	defer p.ready(true)()
-	// picoast_failed_assumption SIGABRTs if the following conditional is true
-	if p.res() != Unsatisfiable || lit == 0 || !p.couldHaveFailedAssumptions {
+	// picosat_failed_assumption aborts unless PicoSAT is in the UNSAT state.
+	if p.state != stateUnsat || lit == 0 || lit == math.MinInt32 {
 		return false
 	}
 	// int picosat_failed_assumption (PicoSAT *, int lit);
@@ -62,14 +96,55 @@ func litArrayToSlice(litPtr *C.int, maxLen int) []Literal {
 // FailedAssumptions returns a list of failed assumptions, i.e., all the
 // for which FailedAssumption returns true. See FailedAssumption.
 func (p *Pigosat) FailedAssumptions() []Literal {
+	lits, _ := p.FailedAssumptionsChecked()
+	return lits
+}
+
+// FailedAssumptionsChecked is like FailedAssumptions, but returns
+// ErrNotUnsatisfiable if the last call to Solve had status other than
+// Unsatisfiable or the assumptions have since become invalid. See Assume.
+func (p *Pigosat) FailedAssumptionsChecked() ([]Literal, error) {
 	defer p.ready(false)() // Overwrites what becomes litPtr below.
-	if p.res() != Unsatisfiable || !p.couldHaveFailedAssumptions {
-		return []Literal{}
+	if p.state != stateUnsat {
+		return []Literal{}, ErrNotUnsatisfiable
 	}
-
 	// const int * picosat_failed_assumptions (PicoSAT *);
 	litPtr := C.picosat_failed_assumptions(p.p)
-	return litArrayToSlice(litPtr, int(C.picosat_variables(p.p)))
+	return litArrayToSlice(litPtr, int(C.picosat_variables(p.p))), nil
+}
+
+// MinUnsatisfiableAssumptions returns a minimal subset of the failed
+// assumptions that still makes the formula unsatisfiable: dropping any one of
+// them makes the rest satisfiable. See FailedAssumptions. If the formula is
+// unsatisfiable even without assumptions, or if the last call to Solve had
+// status other than Unsatisfiable, the result is empty. Computing the subset
+// may call Solve many times internally, but afterward p is in the same state
+// as before, so FailedAssumption and FailedAssumptions still work.
+//
+// Adding one fresh variable to each clause and assuming its negation makes
+// the result a minimal unsatisfiable subset (MUS) of the clauses.
+func (p *Pigosat) MinUnsatisfiableAssumptions() []Literal {
+	lits, _ := p.MinUnsatisfiableAssumptionsChecked()
+	return lits
+}
+
+// MinUnsatisfiableAssumptionsChecked is like MinUnsatisfiableAssumptions, but
+// returns ErrNotUnsatisfiable under the same conditions as
+// FailedAssumptionsChecked.
+func (p *Pigosat) MinUnsatisfiableAssumptionsChecked() ([]Literal, error) {
+	defer p.ready(false)() // Overwrites what becomes litPtr below.
+	// picosat_mus_assumptions aborts unless PicoSAT is in the UNSAT state.
+	if p.state != stateUnsat {
+		return []Literal{}, ErrNotUnsatisfiable
+	}
+	// const int * picosat_mus_assumptions (PicoSAT *, void *,
+	//                                      void(*)(void*,const int*),int);
+	resume := p.suspendHooks()
+	litPtr := C.picosat_mus_assumptions(p.p, nil, nil, 0)
+	resume()
+	// picosat_mus_assumptions ends by solving under the minimal subset, which
+	// leaves PicoSAT in the UNSAT state.
+	return litArrayToSlice(litPtr, int(C.picosat_variables(p.p))), nil
 }
 
 // MaxSatisfiableAssumptions computes a maximal subset of satisfiable
@@ -82,7 +157,13 @@ func (p *Pigosat) MaxSatisfiableAssumptions() []Literal {
 		return []Literal{}
 	}
 	// const int * picosat_maximal_satisfiable_subset_of_assumptions (PicoSAT *);
+	resume := p.suspendHooks()
 	litPtr := C.picosat_maximal_satisfiable_subset_of_assumptions(p.p)
+	resume()
+	// PicoSAT solves and reassumes the assumptions, leaving it in the READY
+	// state if there are any, and in an unpredictable state otherwise. READY
+	// is the state permitting the fewest calls, so assume it.
+	p.state = stateReady
 	return litArrayToSlice(litPtr, int(C.picosat_variables(p.p)))
 }
 
@@ -104,6 +185,9 @@ func (p *Pigosat) NextMaxSatisfiableAssumptions() []Literal {
 	}
 	// const int *
 	// picosat_next_maximal_satisfiable_subset_of_assumptions (PicoSAT *);
+	resume := p.suspendHooks()
 	litPtr := C.picosat_next_maximal_satisfiable_subset_of_assumptions(p.p)
+	resume()
+	p.state = stateReady // See MaxSatisfiableAssumptions.
 	return litArrayToSlice(litPtr, int(C.picosat_variables(p.p)))
 }
@@ -3,8 +3,11 @@
 package pigosat
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -249,3 +252,63 @@ func ExamplePigosat_Assume() {
 	//                 Maximal satisfiable subset of assumptions 3: []
 	//                 Number of clauses: 5
 }
+
+func TestMinUnsatisfiableAssumptions(t *testing.T) {
+	// The clauses {1}, {-1, 2}, {3}, {-2}, {3, -1} are active when their
+	// respective selector variables 4 through 8 are false. The first, second,
+	// and fourth clauses are the only minimal unsatisfiable subset.
+	p, _ := New(nil)
+	p.Add(Formula{{1, 4}, {-1, 2, 5}, {3, 6}, {-2, 7}, {3, -1, 8}})
+	if mus := p.MinUnsatisfiableAssumptions(); len(mus) != 0 {
+		t.Errorf("Expected no assumptions before Solve, got %v", mus)
+	}
+	for v := Literal(4); v <= 8; v++ {
+		p.Assume(-v)
+	}
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected %v, got %v", Unsatisfiable, status)
+	}
+	mus := p.MinUnsatisfiableAssumptions()
+	sort.Slice(mus, func(i, j int) bool { return mus[i] > mus[j] })
+	if expected := []Literal{-4, -5, -7}; !reflect.DeepEqual(mus, expected) {
+		t.Errorf("Expected %v, got %v", expected, mus)
+	}
+	// p stays in the Unsatisfiable state.
+	if !p.FailedAssumption(-4) {
+		t.Errorf("Expected -4 to be a failed assumption")
+	}
+
+	// Once the assumptions are reset, there is no MUS.
+	p.Add(Formula{{1, 2}})
+	if mus := p.MinUnsatisfiableAssumptions(); len(mus) != 0 {
+		t.Errorf("Expected no assumptions after Add, got %v", mus)
+	}
+}
+
+// TestAssumeChecked tests that AssumeChecked rejects invalid literals without
+// assuming them, and that Assume panics on them instead of letting PicoSAT
+// abort.
+func TestAssumeChecked(t *testing.T) {
+	p, _ := New(&Options{MaxVariable: 3})
+	defer p.Delete()
+	p.Add(Formula{{1, 2}})
+	var litErr *LiteralError
+	for _, lit := range []Literal{0, 4, -4, math.MinInt32} {
+		if err := p.AssumeChecked(lit); !errors.As(err, &litErr) || litErr.Literal != lit {
+			t.Errorf("AssumeChecked(%d): expected a *LiteralError, got %v", lit, err)
+		}
+		assertPanics(t, "Assume", func() { p.Assume(lit) })
+	}
+	if err := p.AssumeChecked(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.AssumeChecked(-2); err != nil {
+		t.Fatal(err)
+	}
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Errorf("Expected Unsatisfiable under assumptions, got %v", status)
+	}
+	if _, status := p.Solve(); status != Satisfiable {
+		t.Errorf("Expected Satisfiable without assumptions, got %v", status)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+// #include "picosat.h"
+import "C"
+
+// Backbone returns the literals among candidates that are true in every
+// solution of p's formula, in the order they appear in candidates. If
+// candidates is nil, Backbone considers both literals of every variable. If
+// the formula is unsatisfiable, or if a limit such as Options.PropagationLimit
+// prevents Backbone from finding a first solution, Backbone returns nil. A
+// literal Backbone cannot prove is in every solution within the limit is left
+// out.
+//
+// Backbone calls Solve once and then, for each candidate true in every
+// solution found so far, tries to solve the formula under the assumption that
+// the candidate is false. Each solution found this way rules out the
+// candidates it makes false, and literals PicoSAT has fixed at the top level
+// need no call to Solve at all. Backbone does not change p's formula, but it
+// replaces p's assumptions. Assumptions made before calling Backbone apply
+// only to the first solution. See Assume.
+func (p *Pigosat) Backbone(candidates []Literal) []Literal {
+	defer p.ready(false)()
+	if p.sat() != Satisfiable {
+		return nil
+	}
+	n := Literal(C.picosat_variables(p.p))
+	if candidates == nil {
+		candidates = make([]Literal, 0, 2*n)
+		for v := Literal(1); v <= n; v++ {
+			candidates = append(candidates, v, -v)
+		}
+	}
+
+	// Keep only the candidates the first solution makes true.
+	remaining := make([]Literal, 0, len(candidates))
+	seen := make(map[Literal]bool, len(candidates))
+	for _, lit := range candidates {
+		if lit == 0 || variableOf(lit) > n || seen[lit] {
+			continue
+		}
+		seen[lit] = true
+		// int picosat_deref (PicoSAT *, int lit);
+		if C.picosat_deref(p.p, C.int(lit)) > 0 {
+			remaining = append(remaining, lit)
+		}
+	}
+
+	backbone := make([]Literal, 0, len(remaining))
+	for i, lit := range remaining {
+		if lit == 0 { // A later solution made lit false.
+			continue
+		}
+		// int picosat_deref_toplevel (PicoSAT *, int lit);
+		if C.picosat_deref_toplevel(p.p, C.int(lit)) > 0 {
+			backbone = append(backbone, lit)
+			continue
+		}
+		p.assume(-lit)
+		switch p.sat() {
+		case Unsatisfiable:
+			backbone = append(backbone, lit)
+		case Satisfiable:
+			for j, other := range remaining[i+1:] {
+				if other != 0 && C.picosat_deref(p.p, C.int(other)) < 0 {
+					remaining[i+1+j] = 0
+				}
+			}
+		}
+	}
+	return backbone
+}
@@ -0,0 +1,92 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// bruteBackbone computes the backbone of formula by enumerating its solutions.
+func bruteBackbone(formula Formula) []Literal {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(formula)
+	always := make(map[Literal]bool)
+	first := true
+	p.Enumerate(context.Background(), nil, 0, func(cube []Literal) bool {
+		now := make(map[Literal]bool)
+		for _, lit := range cube {
+			if first || always[lit] {
+				now[lit] = true
+			}
+		}
+		always, first = now, false
+		return true
+	})
+	if first {
+		return nil
+	}
+	backbone := []Literal{}
+	for v := Literal(1); v <= Literal(p.Variables()); v++ {
+		if always[v] {
+			backbone = append(backbone, v)
+		} else if always[-v] {
+			backbone = append(backbone, -v)
+		}
+	}
+	return backbone
+}
+
+func TestBackbone(t *testing.T) {
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			p, _ := New(nil)
+			p.Add(ft.formula)
+			expected := bruteBackbone(ft.formula)
+			if b := p.Backbone(nil); !reflect.DeepEqual(b, expected) {
+				t.Errorf("Expected backbone %v, got %v", expected, b)
+			}
+			if clauses := p.AddedOriginalClauses(); clauses != ft.clauses {
+				t.Errorf("Backbone changed clause count from %d to %d",
+					ft.clauses, clauses)
+			}
+		})
+	}
+
+	formula := Formula{{1}, {-1, 2}, {3, 4}, {-3, 5}, {-4, 5}}
+	tests := []struct {
+		candidates, expected []Literal
+	}{
+		{nil, []Literal{1, 2, 5}},
+		{[]Literal{5, -2, 3, 0, 1, 1, -4, 9}, []Literal{5, 1}},
+		{[]Literal{}, []Literal{}},
+		{[]Literal{-1, 3, -5}, []Literal{}},
+	}
+	for i, bt := range tests {
+		t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+			p, _ := New(nil)
+			p.Add(formula)
+			if b := p.Backbone(bt.candidates); !reflect.DeepEqual(b, bt.expected) {
+				t.Errorf("Expected backbone %v, got %v", bt.expected, b)
+			}
+			// Backbone leaves p usable.
+			if _, status := p.Solve(); status != Satisfiable {
+				t.Errorf("Expected %v after Backbone, got %v", Satisfiable, status)
+			}
+		})
+	}
+}
+
+func ExamplePigosat_Backbone() {
+	p, _ := New(nil)
+	defer p.Delete()
+	// Variable 1 must be true, which forces variable 2 to be true. Either 3 or
+	// 4 is true, and both imply 5.
+	p.Add(Formula{{1}, {-1, 2}, {3, 4}, {-3, 5}, {-4, 5}})
+	fmt.Println(p.Backbone(nil))
+	// Output:
+	// [1 2 5]
+}
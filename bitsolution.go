@@ -0,0 +1,236 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+// #include "picosat.h"
+// #include "helpers.h"
+import "C"
+import (
+	"bytes"
+	"fmt"
+	"math/bits"
+	"unsafe"
+)
+
+// BitSolution is a Solution stored as a bitset, one bit per variable rather
+// than the one byte per variable a Solution takes. Use BitSolution to store
+// many solutions, such as when deduplicating enumerated models. Like Solution,
+// BitSolution is indexed by variable starting at one. The zero value has no
+// variables. Copying a BitSolution copies a reference to its bits, as copying
+// a slice does, so use Copy to get an independent BitSolution.
+type BitSolution struct {
+	words     []uint64 // Bit v%64 of words[v/64] is variable v's value
+	variables int
+}
+
+// NewBitSolution returns a BitSolution of the given number of variables, all
+// false.
+func NewBitSolution(variables int) BitSolution {
+	if variables < 0 {
+		panic(fmt.Errorf("negative number of variables %d", variables))
+	}
+	return BitSolution{make([]uint64, variables/64+1), variables}
+}
+
+// Bits returns s as a BitSolution with len(s)-1 variables.
+func (s Solution) Bits() BitSolution {
+	if len(s) == 0 {
+		return BitSolution{}
+	}
+	b := NewBitSolution(len(s) - 1)
+	for v, value := range s[1:] {
+		if value {
+			b.words[(v+1)/64] |= 1 << uint((v+1)%64)
+		}
+	}
+	return b
+}
+
+// BitSolutionFromLiterals returns the BitSolution setting the variable of each
+// of lits true if the literal is positive and false if it is negative. The
+// number of variables is the largest variable in lits. Variables missing from
+// lits are false, and zeros in lits are ignored. If lits sets a variable both
+// true and false, the last literal wins.
+func BitSolutionFromLiterals(lits []Literal) BitSolution {
+	variables := 0
+	for _, lit := range lits {
+		if v := int(variableOf(lit)); v > variables {
+			variables = v
+		}
+	}
+	b := NewBitSolution(variables)
+	for _, lit := range lits {
+		if lit != 0 {
+			b.Set(variableOf(lit), lit > 0)
+		}
+	}
+	return b
+}
+
+// Variables returns the number of variables b holds.
+func (b BitSolution) Variables() int {
+	return b.variables
+}
+
+// check panics if variable v is out of b's range.
+func (b BitSolution) check(v Literal) {
+	if v < 1 || int(v) > b.variables {
+		panic(fmt.Errorf("variable %d out of range [1, %d]", v, b.variables))
+	}
+}
+
+// Get returns variable v's value. Get panics if v is not between 1 and
+// b.Variables().
+func (b BitSolution) Get(v Literal) bool {
+	b.check(v)
+	return b.words[v/64]&(1<<uint(v%64)) != 0
+}
+
+// Set sets variable v's value. Set panics if v is not between 1 and
+// b.Variables().
+func (b BitSolution) Set(v Literal, value bool) {
+	b.check(v)
+	if value {
+		b.words[v/64] |= 1 << uint(v%64)
+	} else {
+		b.words[v/64] &^= 1 << uint(v%64)
+	}
+}
+
+// Count returns the number of true variables.
+func (b BitSolution) Count() int {
+	count := 0
+	for _, w := range b.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// word returns b.words[i], or zero if b has no such word, as the zero
+// BitSolution does not.
+func (b BitSolution) word(i int) uint64 {
+	if i < len(b.words) {
+		return b.words[i]
+	}
+	return 0
+}
+
+// Equal reports whether b and c have the same number of variables and the
+// same values.
+func (b BitSolution) Equal(c BitSolution) bool {
+	if b.variables != c.variables {
+		return false
+	}
+	for i := 0; i <= b.variables/64; i++ {
+		if b.word(i) != c.word(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Hash returns a 64-bit FNV-1a hash of b's variables and values, for use as a
+// map key or in a hash set. Equal BitSolutions have equal hashes.
+func (b BitSolution) Hash() uint64 {
+	const prime = 1099511628211
+	h := uint64(14695981039346656037)
+	h = (h ^ uint64(b.variables)) * prime
+	for j := 0; j <= b.variables/64; j++ {
+		w := b.word(j)
+		for i := uint(0); i < 64; i += 8 {
+			h = (h ^ (w >> i & 0xff)) * prime
+		}
+	}
+	return h
+}
+
+// Copy returns a BitSolution equal to b that shares none of b's memory.
+func (b BitSolution) Copy() BitSolution {
+	return BitSolution{append([]uint64(nil), b.words...), b.variables}
+}
+
+// Solution returns b as a Solution of length b.Variables()+1.
+func (b BitSolution) Solution() Solution {
+	s := make(Solution, b.variables+1)
+	for v := 1; v <= b.variables; v++ {
+		s[v] = b.words[v/64]&(1<<uint(v%64)) != 0
+	}
+	return s
+}
+
+// Literals returns b as a list of literals, one per variable in increasing
+// order, positive if the variable is true and negative if it is false.
+func (b BitSolution) Literals() []Literal {
+	lits := make([]Literal, b.variables)
+	for v := 1; v <= b.variables; v++ {
+		if b.words[v/64]&(1<<uint(v%64)) != 0 {
+			lits[v-1] = Literal(v)
+		} else {
+			lits[v-1] = Literal(-v)
+		}
+	}
+	return lits
+}
+
+// String returns a readable string like "{1:true, 2:false, ...}", as
+// Solution.String does.
+func (b BitSolution) String() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for v := 1; v <= b.variables; v++ {
+		if v > 1 {
+			buffer.WriteString(", ")
+		}
+		fmt.Fprintf(&buffer, "%d:%v", v, b.words[v/64]&(1<<uint(v%64)) != 0)
+	}
+	buffer.WriteString("}")
+	return buffer.String()
+}
+
+// SolveBits is like SolveInto, but stores the solution in a BitSolution. If
+// buf's memory has room for p.Variables() variables, SolveBits reuses it, and
+// otherwise allocates. If the status is not Satisfiable, the solution is the
+// zero BitSolution and buf is unchanged.
+func (p *Pigosat) SolveBits(buf BitSolution) (solution BitSolution, status Status) {
+	defer p.ready(false)()
+	if status = p.sat(); status != Satisfiable {
+		return
+	}
+	n := int(C.picosat_variables(p.p))
+	if words := n/64 + 1; cap(buf.words) >= words {
+		solution = BitSolution{buf.words[:words], n}
+		for i := range solution.words {
+			solution.words[i] = 0
+		}
+	} else {
+		solution = NewBitSolution(n)
+	}
+	// int pigosat_deref_bits (PicoSAT *, uint64_t * words, int n);
+	if v := C.pigosat_deref_bits(p.p,
+		(*C.uint64_t)(unsafe.Pointer(&solution.words[0])), C.int(n)); v != 0 {
+		panic(fmt.Errorf("Variable %d was assigned value 0", v))
+	}
+	return
+}
+
+// BlockBitSolution is like BlockSolution, but takes a BitSolution. It returns
+// a *SolutionLengthError if solution's number of variables differs from p's.
+func (p *Pigosat) BlockBitSolution(solution BitSolution) error {
+	defer p.ready(false)()
+	n := int(C.picosat_variables(p.p))
+	if solution.variables != n {
+		return &SolutionLengthError{Length: solution.variables + 1, Variables: n}
+	}
+	clause := make([]C.int, n+1)
+	for v := 1; v <= n; v++ {
+		if solution.words[v/64]&(1<<uint(v%64)) != 0 {
+			clause[v-1] = C.int(-v)
+		} else {
+			clause[v-1] = C.int(v)
+		}
+	}
+	p.state = stateReady
+	// int picosat_add_lits (PicoSAT *, int * lits);
+	C.picosat_add_lits(p.p, &clause[0])
+	return nil
+}
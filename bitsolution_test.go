@@ -0,0 +1,113 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBitSolution(t *testing.T) {
+	s := make(Solution, 131)
+	for _, v := range []int{1, 2, 63, 64, 65, 127, 128, 130} {
+		s[v] = true
+	}
+	b := s.Bits()
+	if b.Variables() != 130 {
+		t.Errorf("Expected 130 variables, got %d", b.Variables())
+	}
+	if b.Count() != 8 {
+		t.Errorf("Expected 8 true variables, got %d", b.Count())
+	}
+	for v := 1; v <= 130; v++ {
+		if b.Get(Literal(v)) != s[v] {
+			t.Errorf("Variable %d: expected %v", v, s[v])
+		}
+	}
+	if !reflect.DeepEqual(b.Solution(), s) {
+		t.Errorf("Solution() = %v, expected %v", b.Solution(), s)
+	}
+	lits := b.Literals()
+	if len(lits) != 130 || lits[0] != 1 || lits[2] != -3 || lits[129] != 130 {
+		t.Errorf("Unexpected literals %v", lits)
+	}
+	if c := BitSolutionFromLiterals(lits); !c.Equal(b) || c.Hash() != b.Hash() {
+		t.Errorf("BitSolutionFromLiterals(b.Literals()) = %v != %v", c, b)
+	}
+
+	c := b.Copy()
+	c.Set(64, false)
+	if c.Equal(b) || !b.Get(64) || c.Get(64) {
+		t.Error("Copy shares memory or Set failed")
+	}
+	if c.Hash() == b.Hash() {
+		t.Error("Unequal solutions have equal hashes")
+	}
+	c.Set(64, true)
+	if !c.Equal(b) {
+		t.Error("Set failed to restore the value")
+	}
+	if b.Equal(NewBitSolution(131)) || NewBitSolution(3).Equal(NewBitSolution(4)) {
+		t.Error("Solutions with different numbers of variables are equal")
+	}
+	if d := BitSolutionFromLiterals([]Literal{-4, 2, 0, 2, -2}); d.Variables() != 4 ||
+		d.Count() != 0 {
+		t.Errorf("Unexpected %v from literals with duplicates", d)
+	}
+	if (Solution{}).Bits().Variables() != 0 || NewBitSolution(0).Count() != 0 {
+		t.Error("Empty solutions should have no variables")
+	}
+	// The zero BitSolution equals, and hashes like, other empty ones.
+	for _, e := range []BitSolution{{}, (Solution{}).Bits(), (Solution{false}).Bits(),
+		NewBitSolution(0), BitSolutionFromLiterals(nil)} {
+		if !e.Equal(BitSolution{}) || !(BitSolution{}).Equal(e) ||
+			e.Hash() != (BitSolution{}).Hash() {
+			t.Errorf("%#v differs from the zero BitSolution", e)
+		}
+	}
+	if str := (Solution{false, true, false}).Bits().String(); str != "{1:true, 2:false}" {
+		t.Errorf("String() = %q", str)
+	}
+	assertPanics(t, "Get", func() { b.Get(0) })
+	assertPanics(t, "Get", func() { b.Get(131) })
+	assertPanics(t, "Set", func() { b.Set(-1, true) })
+	assertPanics(t, "NewBitSolution", func() { NewBitSolution(-1) })
+}
+
+// TestSolveBits tests that SolveBits and BlockBitSolution enumerate the same
+// solutions as Solve and BlockSolution.
+func TestSolveBits(t *testing.T) {
+	for _, ft := range formulaTests {
+		p, _ := New(nil)
+		q, _ := New(nil)
+		p.Add(ft.formula)
+		q.Add(ft.formula)
+		var buf BitSolution
+		for {
+			want, wantStatus := p.Solve()
+			got, status := q.SolveBits(buf)
+			if status != wantStatus {
+				t.Fatalf("SolveBits status %v != Solve status %v", status, wantStatus)
+			}
+			if status != Satisfiable {
+				break
+			}
+			if !reflect.DeepEqual(got.Solution(), want) {
+				t.Errorf("SolveBits = %v != %v = Solve", got, want)
+			}
+			if buf.words != nil && &got.words[0] != &buf.words[0] {
+				t.Error("SolveBits allocated despite enough capacity")
+			}
+			buf = got
+			p.BlockSolution(want)
+			if err := q.BlockBitSolution(got); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := q.BlockBitSolution(NewBitSolution(ft.variables + 1)); err == nil {
+			t.Error("Expected an error blocking a solution of the wrong length")
+		}
+		p.Delete()
+		q.Delete()
+	}
+}
@@ -0,0 +1,506 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+// Command pigosat solves satisfiability problems in DIMACS CNF format with
+// PiGoSAT, the same Go bindings to PicoSAT that programs importing package
+// github.com/wkschwartz/pigosat use.
+//
+// Usage:
+//
+//	pigosat solve [flags] [file]
+//	pigosat core -o output [flags] [file]
+//	pigosat trace -o output [-extended] [flags] [file]
+//	pigosat mus -o output [flags] [file]
+//	pigosat enumerate [-format dimacs|json] [-max n] [-timeout d] [flags] [file]
+//	pigosat count [file]
+//
+// Each subcommand reads a formula from file, or from standard input if file is
+// missing or "-", decompressing it if it is compressed with gzip, bzip2, or xz,
+// and prints the result in the format of the SAT competitions:
+// comment lines start with "c", the status line is one of "s SATISFIABLE",
+// "s UNSATISFIABLE", or "s UNKNOWN", and the solution of a satisfiable formula
+// follows on lines starting with "v" and ending with "0". The exit status is
+// 10 for satisfiable formulas, 20 for unsatisfiable formulas, 0 when the
+// status is unknown, and 1 on errors.
+//
+// The core, trace, and mus subcommands help debug unsatisfiable formulas. If
+// the formula is unsatisfiable, they write to the output file the clausal core
+// in DIMACS format, a proof trace in TraceCheck format (compact unless
+// -extended is set), or a minimal unsatisfiable subset of the clauses in
+// DIMACS format, respectively.
+//
+// The enumerate subcommand streams every solution of the formula, either as
+// one "v" line per solution or, with -format json, as one JSON array of
+// literals per line. The count subcommand prints the number of solutions on a
+// line "c s exact arb int <count>" after the status line. If the input has
+// comment lines "c ind <variable> ... 0", both subcommands consider only the
+// listed variables, so solutions differing only on other variables count as
+// one. Otherwise they consider every variable up to the header's variable
+// count. Run "pigosat <command> -h" for the flags.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/wkschwartz/pigosat"
+)
+
+// Exit statuses, following the SAT competitions.
+const (
+	exitUnknown       = 0
+	exitError         = 1
+	exitSatisfiable   = 10
+	exitUnsatisfiable = 20
+)
+
+// usage is printed when pigosat gets no subcommand or an unknown one.
+const usage = `usage: pigosat <command> [flags] [file]
+
+Commands:
+  solve   solve a DIMACS CNF formula
+  core    write the clausal core of an unsatisfiable formula
+  trace   write a proof trace of an unsatisfiable formula
+  mus     write a minimal unsatisfiable subset of a formula's clauses
+  enumerate
+          print every solution of a formula
+  count   print the number of solutions of a formula
+
+Run "pigosat <command> -h" for the flags of each command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command line args and returns the exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	switch args[0] {
+	case "solve":
+		return solve(args[1:], stdin, stdout, stderr)
+	case "core":
+		return core(args[1:], stdin, stdout, stderr)
+	case "trace":
+		return trace(args[1:], stdin, stdout, stderr)
+	case "mus":
+		return mus(args[1:], stdin, stdout, stderr)
+	case "enumerate":
+		return enumerate(args[1:], stdin, stdout, stderr)
+	case "count":
+		return count(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return exitUnknown
+	}
+	fmt.Fprintf(stderr, "pigosat: unknown command %q\n\n%s", args[0], usage)
+	return exitError
+}
+
+// solverFlags registers the flags that configure pigosat.Options on fs.
+func solverFlags(fs *flag.FlagSet) func() *pigosat.Options {
+	limit := fs.Uint64("propagation-limit", 0,
+		"give up after this many propagations (0 means no limit)")
+	verbosity := fs.Uint("v", 0, "verbosity level of PicoSAT's progress reports")
+	seed := fs.Uint("seed", 0, "seed for PicoSAT's random decisions")
+	return func() *pigosat.Options {
+		return &pigosat.Options{
+			PropagationLimit: *limit,
+			Verbosity:        *verbosity,
+			Seed:             uint32(*seed),
+		}
+	}
+}
+
+// readFormula reads a DIMACS formula from the file named by fs's only
+// argument, or from stdin if there is no argument or it is "-".
+func readFormula(fs *flag.FlagSet, stdin io.Reader) (pigosat.Formula, int, error) {
+	formula, variables, _, err := readProjection(fs, stdin)
+	return formula, variables, err
+}
+
+// readProjection is like readFormula, but also returns the variables in the
+// input's "c ind" lines, or every variable if there are no such lines.
+func readProjection(fs *flag.FlagSet, stdin io.Reader) (formula pigosat.Formula,
+	variables int, projection []pigosat.Literal, err error) {
+	switch fs.NArg() {
+	case 0:
+		formula, variables, projection, err = pigosat.ReadDIMACSProjection(stdin)
+	case 1:
+		if fs.Arg(0) == "-" {
+			formula, variables, projection, err = pigosat.ReadDIMACSProjection(stdin)
+			break
+		}
+		var f *os.File
+		if f, err = os.Open(fs.Arg(0)); err != nil {
+			return
+		}
+		defer f.Close()
+		formula, variables, projection, err = pigosat.ReadDIMACSProjection(f)
+		if err != nil {
+			err = fmt.Errorf("%s: %v", fs.Arg(0), err)
+		}
+	default:
+		err = fmt.Errorf("expected at most one file, got %d", fs.NArg())
+	}
+	if err == nil && projection == nil {
+		projection = make([]pigosat.Literal, variables)
+		for i := range projection {
+			projection[i] = pigosat.Literal(i + 1)
+		}
+	}
+	return
+}
+
+// newSolver returns a Pigosat instance with options, sending any verbose
+// output to stdout if it is a file. It accepts every variable, not just up to
+// pigosat.DefaultMaxVariable, because the input's header declares how many
+// variables the formula has.
+func newSolver(options *pigosat.Options, stdout io.Writer) (*pigosat.Pigosat, error) {
+	if f, ok := stdout.(*os.File); ok {
+		options.OutputFile = f
+	}
+	options.MaxVariable = math.MaxInt32
+	return pigosat.New(options)
+}
+
+// solve implements the solve subcommand.
+func solve(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pigosat solve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	options := solverFlags(fs)
+	trace := fs.String("trace", "",
+		"write a compact TraceCheck proof trace to this file if unsatisfiable")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	opts := options()
+	opts.EnableTrace = *trace != ""
+	return solveWith(fs, opts, stdin, stdout, stderr,
+		func(p *pigosat.Pigosat) error {
+			if *trace == "" {
+				return nil
+			}
+			return writeFile(*trace, p.WriteCompactTrace)
+		})
+}
+
+// core implements the core subcommand.
+func core(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pigosat core", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	options := solverFlags(fs)
+	output := fs.String("o", "", "write the clausal core to this file (required)")
+	if err := parseWithOutput(fs, args, output, stderr); err != nil {
+		return exitError
+	}
+	opts := options()
+	opts.EnableTrace = true
+	return solveWith(fs, opts, stdin, stdout, stderr,
+		func(p *pigosat.Pigosat) error {
+			return writeFile(*output, p.WriteClausalCore)
+		})
+}
+
+// trace implements the trace subcommand.
+func trace(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pigosat trace", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	options := solverFlags(fs)
+	output := fs.String("o", "", "write the proof trace to this file (required)")
+	extended := fs.Bool("extended", false,
+		"write an extended trace instead of a compact one")
+	if err := parseWithOutput(fs, args, output, stderr); err != nil {
+		return exitError
+	}
+	opts := options()
+	opts.EnableTrace = true
+	return solveWith(fs, opts, stdin, stdout, stderr,
+		func(p *pigosat.Pigosat) error {
+			if *extended {
+				return writeFile(*output, p.WriteExtendedTrace)
+			}
+			return writeFile(*output, p.WriteCompactTrace)
+		})
+}
+
+// mus implements the mus subcommand. To find a minimal unsatisfiable subset of
+// the clauses, it adds a fresh selector variable to each clause, so assuming
+// the selector is false activates the clause, and then minimizes the failed
+// assumptions.
+func mus(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pigosat mus", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	options := solverFlags(fs)
+	output := fs.String("o", "",
+		"write the minimal unsatisfiable subset to this file (required)")
+	if err := parseWithOutput(fs, args, output, stderr); err != nil {
+		return exitError
+	}
+	formula, variables, err := readFormula(fs, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	p, err := newSolver(options(), stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	defer p.Delete()
+	selected := make(pigosat.Formula, len(formula))
+	for i, clause := range formula {
+		selector := pigosat.Literal(variables + i + 1)
+		selected[i] = append(append(pigosat.Clause{}, clause...), selector)
+	}
+	p.Add(selected)
+	for i := range formula {
+		p.Assume(-pigosat.Literal(variables + i + 1))
+	}
+	solution, status := p.Solve()
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	exit := writeStatus(w, status)
+	if status == pigosat.Satisfiable {
+		writeSolution(w, solution, variables)
+	}
+	if status != pigosat.Unsatisfiable {
+		return exit
+	}
+	assumptions := p.MinUnsatisfiableAssumptions()
+	subset := make(pigosat.Formula, 0, len(assumptions))
+	for _, lit := range assumptions {
+		subset = append(subset, formula[int(-lit)-variables-1])
+	}
+	err = writeFile(*output, func(w io.Writer) error {
+		return pigosat.WriteDIMACS(w, subset)
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	return exit
+}
+
+// enumerate implements the enumerate subcommand.
+func enumerate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pigosat enumerate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	options := solverFlags(fs)
+	format := fs.String("format", "dimacs",
+		`"dimacs" to print solutions as "v" lines or "json" for JSON arrays`)
+	max := fs.Int("max", 0, "stop after this many solutions (0 means no limit)")
+	timeout := fs.Duration("timeout", 0,
+		"stop starting new searches after this long (0 means no limit)")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if *format != "dimacs" && *format != "json" {
+		fmt.Fprintf(stderr, "pigosat: unknown format %q\n", *format)
+		return exitError
+	}
+	formula, _, projection, err := readProjection(fs, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	p, err := newSolver(options(), stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	defer p.Delete()
+	p.Add(formula)
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	var buf []byte
+	count, status := p.Enumerate(ctx, projection, *max,
+		func(cube []pigosat.Literal) bool {
+			if *format == "json" {
+				buf = append(buf[:0], '[')
+				for i, lit := range cube {
+					if i > 0 {
+						buf = append(buf, ',')
+					}
+					buf = strconv.AppendInt(buf, int64(lit), 10)
+				}
+				buf = append(buf, ']', '\n')
+			} else {
+				buf = append(buf[:0], 'v')
+				for _, lit := range cube {
+					buf = strconv.AppendInt(append(buf, ' '), int64(lit), 10)
+				}
+				buf = append(buf, " 0\n"...)
+			}
+			w.Write(buf)
+			return true
+		})
+
+	switch {
+	case count > 0:
+		status = pigosat.Satisfiable
+	case status != pigosat.Unsatisfiable:
+		status = pigosat.Unknown
+	}
+	if *format == "json" {
+		return exitStatus(status)
+	}
+	fmt.Fprintf(w, "c %d solutions\n", count)
+	if count > 0 && *max > 0 && count == *max {
+		fmt.Fprintln(w, "c stopped at the maximum number of solutions")
+	} else if ctx.Err() != nil {
+		fmt.Fprintln(w, "c stopped at the timeout")
+	}
+	return writeStatus(w, status)
+}
+
+// count implements the count subcommand.
+func count(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pigosat count", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	formula, _, projection, err := readProjection(fs, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	n := pigosat.CountModels(formula, projection)
+	status := pigosat.Unsatisfiable
+	if n.Sign() > 0 {
+		status = pigosat.Satisfiable
+	}
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	exit := writeStatus(w, status)
+	fmt.Fprintf(w, "c s exact arb int %v\n", n)
+	return exit
+}
+
+// parseWithOutput parses args with fs and requires that the flag that sets
+// output is not empty. Errors go to stderr.
+func parseWithOutput(fs *flag.FlagSet, args []string, output *string,
+	stderr io.Writer) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		err := fmt.Errorf("missing required flag -o")
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		fs.Usage()
+		return err
+	}
+	return nil
+}
+
+// solveWith solves the formula that readFormula reads for fs with options and
+// writes the result to stdout. If the formula is unsatisfiable, solveWith then
+// calls unsat.
+func solveWith(fs *flag.FlagSet, options *pigosat.Options, stdin io.Reader,
+	stdout, stderr io.Writer,
+	unsat func(*pigosat.Pigosat) error) int {
+	formula, variables, err := readFormula(fs, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	p, err := newSolver(options, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "pigosat: %v\n", err)
+		return exitError
+	}
+	defer p.Delete()
+	p.Add(formula)
+
+	w := bufio.NewWriter(stdout)
+	defer w.Flush()
+	if options.Verbosity > 0 {
+		fmt.Fprintf(w, "c pigosat %s (PicoSAT %s)\n", pigosat.Version,
+			pigosat.PicosatVersion)
+		w.Flush() // PicoSAT's reports go straight to stdout.
+	}
+	solution, status := p.Solve()
+	exit := writeStatus(w, status)
+	switch status {
+	case pigosat.Satisfiable:
+		writeSolution(w, solution, variables)
+	case pigosat.Unsatisfiable:
+		if err := unsat(p); err != nil {
+			fmt.Fprintf(stderr, "pigosat: %v\n", err)
+			return exitError
+		}
+	}
+	return exit
+}
+
+// writeStatus writes the status line for status and returns the exit status.
+func writeStatus(w io.Writer, status pigosat.Status) int {
+	switch status {
+	case pigosat.Satisfiable:
+		fmt.Fprintln(w, "s SATISFIABLE")
+	case pigosat.Unsatisfiable:
+		fmt.Fprintln(w, "s UNSATISFIABLE")
+	default:
+		fmt.Fprintln(w, "s UNKNOWN")
+	}
+	return exitStatus(status)
+}
+
+// exitStatus returns the exit status for status.
+func exitStatus(status pigosat.Status) int {
+	switch status {
+	case pigosat.Satisfiable:
+		return exitSatisfiable
+	case pigosat.Unsatisfiable:
+		return exitUnsatisfiable
+	}
+	return exitUnknown
+}
+
+// writeSolution writes solution as "v" lines of at most about 80 characters.
+// Variables up to variables that the solution does not mention are false.
+func writeSolution(w io.Writer, solution pigosat.Solution, variables int) {
+	const width = 78
+	line := []byte("v")
+	for v := 1; v <= variables; v++ {
+		lit := strconv.Itoa(v)
+		if v >= len(solution) || !solution[v] {
+			lit = "-" + lit
+		}
+		if len(line)+1+len(lit) > width {
+			fmt.Fprintf(w, "%s\n", line)
+			line = line[:1]
+		}
+		line = append(append(line, ' '), lit...)
+	}
+	fmt.Fprintf(w, "%s 0\n", line)
+}
+
+// writeFile creates the file named name and writes to it with write.
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return f.Close()
+}
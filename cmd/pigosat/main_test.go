@@ -0,0 +1,254 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runWith runs the command line args with stdin as standard input and returns
+// the exit status, standard output, and standard error.
+func runWith(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+// tempDir creates a temporary directory and returns it and a function that
+// removes it.
+func tempDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "pigosat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+const (
+	satCNF   = "c satisfiable\np cnf 3 2\n1 -2 0\n2 0\n"
+	unsatCNF = "p cnf 2 4\n1 2 0\n-1 2 0\n1 -2 0\n-1 -2 0\n"
+)
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		args   []string
+		stdin  string
+		status int
+		stdout string
+	}{
+		{[]string{"solve"}, satCNF, exitSatisfiable, "s SATISFIABLE\nv 1 2 -3 0\n"},
+		{[]string{"solve", "-"}, satCNF, exitSatisfiable, "s SATISFIABLE\nv 1 2 -3 0\n"},
+		{[]string{"solve", "-seed", "7"}, unsatCNF, exitUnsatisfiable, "s UNSATISFIABLE\n"},
+		{[]string{"solve", "-propagation-limit", "1"},
+			"p cnf 5 3\n1 -5 4 0\n-1 5 3 4 0\n-3 -4 0\n", exitUnknown, "s UNKNOWN\n"},
+		{[]string{"solve"}, "p cnf 0 0\n", exitSatisfiable, "s SATISFIABLE\nv 0\n"},
+	}
+	for _, st := range tests {
+		status, stdout, stderr := runWith(st.args, st.stdin)
+		if status != st.status || stdout != st.stdout {
+			t.Errorf("%v: expected %d, %q; got %d, %q (stderr %q)", st.args,
+				st.status, st.stdout, status, stdout, stderr)
+		}
+	}
+}
+
+func TestSolveFiles(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	cnf := filepath.Join(dir, "unsat.cnf")
+	if err := ioutil.WriteFile(cnf, []byte(unsatCNF), 0666); err != nil {
+		t.Fatal(err)
+	}
+	trace := filepath.Join(dir, "trace")
+	status, stdout, stderr := runWith([]string{"solve", "-trace", trace, cnf}, "")
+	if status != exitUnsatisfiable || stdout != "s UNSATISFIABLE\n" {
+		t.Errorf("Expected %d, unsatisfiable; got %d, %q (stderr %q)",
+			exitUnsatisfiable, status, stdout, stderr)
+	}
+	if b, err := ioutil.ReadFile(trace); err != nil || len(b) == 0 {
+		t.Errorf("Trace not written: %q, %v", b, err)
+	}
+}
+
+func TestDebugCommands(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	// Only the first, second, and fourth clauses are needed for
+	// unsatisfiability.
+	const cnf = "p cnf 3 5\n1 0\n-1 2 0\n3 0\n-2 0\n3 -1 0\n"
+	tests := []struct {
+		args   []string
+		prefix string // Expected start of the output file
+	}{
+		{[]string{"core"}, "p cnf"},
+		{[]string{"trace"}, "1 "},
+		{[]string{"trace", "-extended"}, "1 "},
+		{[]string{"mus"}, "p cnf 2 3\n1 0\n-1 2 0\n-2 0\n"},
+	}
+	for i, dt := range tests {
+		output := filepath.Join(dir, fmt.Sprintf("output%d", i))
+		args := append(dt.args, "-o", output)
+		status, stdout, stderr := runWith(args, cnf)
+		if status != exitUnsatisfiable || stdout != "s UNSATISFIABLE\n" {
+			t.Errorf("%v: expected %d, unsatisfiable; got %d, %q (stderr %q)",
+				dt.args, exitUnsatisfiable, status, stdout, stderr)
+		}
+		b, err := ioutil.ReadFile(output)
+		if err != nil || !strings.HasPrefix(string(b), dt.prefix) {
+			t.Errorf("%v: expected output starting %q, got %q, %v", dt.args,
+				dt.prefix, b, err)
+		}
+
+		// Satisfiable formulas do not write the output file.
+		output += "-sat"
+		args = append(dt.args, "-o", output)
+		status, stdout, stderr = runWith(args, satCNF)
+		if status != exitSatisfiable || stdout != "s SATISFIABLE\nv 1 2 -3 0\n" {
+			t.Errorf("%v: expected %d, satisfiable; got %d, %q (stderr %q)",
+				dt.args, exitSatisfiable, status, stdout, stderr)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("%v: output written for satisfiable formula", dt.args)
+		}
+
+		// The output file is required.
+		if status, _, stderr := runWith(dt.args, cnf); status != exitError || stderr == "" {
+			t.Errorf("%v: expected error without -o, got %d, %q", dt.args,
+				status, stderr)
+		}
+	}
+
+	// An empty clause is its own minimal unsatisfiable subset.
+	output := filepath.Join(dir, "empty")
+	runWith([]string{"mus", "-o", output}, "p cnf 2 3\n1 2 0\n0\n-1 0\n")
+	if b, err := ioutil.ReadFile(output); err != nil || string(b) != "p cnf 0 1\n0\n" {
+		t.Errorf("Expected the empty clause, got %q, %v", b, err)
+	}
+}
+
+func TestSolveLongSolution(t *testing.T) {
+	var cnf bytes.Buffer
+	cnf.WriteString("p cnf 100 100\n")
+	for v := 1; v <= 100; v++ {
+		fmt.Fprintf(&cnf, "-%d 0\n", v)
+	}
+	status, stdout, _ := runWith([]string{"solve"}, cnf.String())
+	if status != exitSatisfiable {
+		t.Fatalf("Expected %d, got %d", exitSatisfiable, status)
+	}
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	var lits []string
+	for _, line := range lines[1:] {
+		if len(line) > 80 || !strings.HasPrefix(line, "v ") {
+			t.Errorf("Bad solution line %q", line)
+		}
+		lits = append(lits, strings.Fields(line)[1:]...)
+	}
+	if len(lits) != 101 || lits[0] != "-1" || lits[99] != "-100" || lits[100] != "0" {
+		t.Errorf("Bad solution %v", lits)
+	}
+}
+
+func TestEnumerate(t *testing.T) {
+	projected := "c ind 1 2 0\n" + satCNF
+	tests := []struct {
+		args   []string
+		stdin  string
+		status int
+		stdout string
+	}{
+		{[]string{"enumerate"}, projected, exitSatisfiable,
+			"v 1 2 0\nc 1 solutions\ns SATISFIABLE\n"},
+		{[]string{"enumerate", "-format", "json"}, projected, exitSatisfiable,
+			"[1,2]\n"},
+		{[]string{"enumerate", "-max", "1"}, satCNF, exitSatisfiable,
+			"c 1 solutions\nc stopped at the maximum number of solutions\n" +
+				"s SATISFIABLE\n"},
+		{[]string{"enumerate"}, unsatCNF, exitUnsatisfiable,
+			"c 0 solutions\ns UNSATISFIABLE\n"},
+		{[]string{"enumerate", "-format", "json"}, unsatCNF, exitUnsatisfiable, ""},
+		{[]string{"enumerate", "-timeout", "1ns"}, satCNF, exitUnknown,
+			"c 0 solutions\nc stopped at the timeout\ns UNKNOWN\n"},
+	}
+	for _, et := range tests {
+		status, stdout, stderr := runWith(et.args, et.stdin)
+		if et.args[len(et.args)-1] == "1" { // Drop the solution, which may vary.
+			stdout = stdout[strings.Index(stdout, "\n")+1:]
+		}
+		if status != et.status || stdout != et.stdout {
+			t.Errorf("%v: expected %d, %q; got %d, %q (stderr %q)", et.args,
+				et.status, et.stdout, status, stdout, stderr)
+		}
+	}
+
+	// Without "c ind" lines, every variable in the header counts.
+	status, stdout, _ := runWith([]string{"enumerate"}, satCNF)
+	lines := strings.Split(stdout, "\n")
+	if status != exitSatisfiable || len(lines) != 5 ||
+		!(lines[0] == "v 1 2 3 0" && lines[1] == "v 1 2 -3 0" ||
+			lines[0] == "v 1 2 -3 0" && lines[1] == "v 1 2 3 0") ||
+		lines[2] != "c 2 solutions" {
+		t.Errorf("enumerate: got %d, %q", status, stdout)
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		stdin  string
+		status int
+		stdout string
+	}{
+		{satCNF, exitSatisfiable, "s SATISFIABLE\nc s exact arb int 2\n"},
+		{"c ind 1 2 0\n" + satCNF, exitSatisfiable,
+			"s SATISFIABLE\nc s exact arb int 1\n"},
+		{"p cnf 70 0\n", exitSatisfiable,
+			"s SATISFIABLE\nc s exact arb int 1180591620717411303424\n"},
+		{unsatCNF, exitUnsatisfiable, "s UNSATISFIABLE\nc s exact arb int 0\n"},
+	}
+	for _, ct := range tests {
+		status, stdout, stderr := runWith([]string{"count"}, ct.stdin)
+		if status != ct.status || stdout != ct.stdout {
+			t.Errorf("%q: expected %d, %q; got %d, %q (stderr %q)", ct.stdin,
+				ct.status, ct.stdout, status, stdout, stderr)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"bogus"},
+		{"solve", "-bogus"},
+		{"solve", "a", "b"},
+		{"solve", filepath.Join("does", "not", "exist.cnf")},
+		{"core", "-o", filepath.Join("does", "not", "exist")},
+		{"mus", "-o", filepath.Join("does", "not", "exist")},
+		{"enumerate", "-format", "xml"},
+		{"enumerate", "a", "b"},
+		{"count", "-bogus"},
+		{"count", "a", "b"},
+	}
+	for _, args := range tests {
+		if status, _, stderr := runWith(args, unsatCNF); status != exitError || stderr == "" {
+			t.Errorf("%v: expected status %d and an error message, got %d, %q",
+				args, exitError, status, stderr)
+		}
+	}
+	if status, _, stderr := runWith([]string{"solve"}, "p cnf 1 1\n2 0\n"); status != exitError || stderr == "" {
+		t.Errorf("Bad DIMACS: expected status %d and an error message, got %d, %q",
+			exitError, status, stderr)
+	}
+	if status, stdout, _ := runWith([]string{"help"}, ""); status != exitUnknown || stdout == "" {
+		t.Errorf("help: got %d, %q", status, stdout)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Compression is a compression format for DIMACS files.
+type Compression int
+
+const (
+	// NoCompression means the data are not compressed.
+	NoCompression Compression = iota
+	// Gzip is the gzip format, conventionally with extension .gz.
+	Gzip
+	// Bzip2 is the bzip2 format, conventionally with extension .bz2.
+	Bzip2
+	// XZ is the xz format, conventionally with extension .xz.
+	XZ
+)
+
+// String returns the name of c's format.
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	case Bzip2:
+		return "bzip2"
+	case XZ:
+		return "xz"
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// Magic numbers at the start of compressed data.
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// Decompress detects whether r's data are compressed by looking for the magic
+// numbers of gzip, bzip2, and xz at the start, and returns a reader of the
+// decompressed data and the format it found. ReadDIMACS, ReadDIMACSProjection,
+// ReadQDIMACS, and ReadWCNF call Decompress, so you need not call it yourself
+// to read compressed files with them. Decompress supports xz data using the
+// LZMA2 filter, which is xz's default, and not xz's other filters.
+func Decompress(r io.Reader) (io.Reader, Compression, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(xzMagic))
+	if err != nil && err != io.EOF {
+		return nil, NoCompression, err
+	}
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, Gzip, err
+		}
+		return gr, Gzip, nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(br), Bzip2, nil
+	case bytes.HasPrefix(magic, xzMagic):
+		return newXZReader(br), XZ, nil
+	}
+	return br, NoCompression, nil
+}
+
+// PrintCompressed is like Print, but compresses its output in format c.
+// PrintCompressed supports NoCompression and Gzip, and returns an error for
+// Bzip2, XZ, and other formats. Go's standard library can read bzip2 but not
+// write it, and has no xz support at all. PiGoSAT implements only the xz
+// decoder Decompress needs rather than depend on third-party compressors.
+func (p *Pigosat) PrintCompressed(w io.Writer, c Compression) error {
+	switch c {
+	case NoCompression:
+		return p.Print(w)
+	case Gzip:
+		gw := gzip.NewWriter(w)
+		if err := p.Print(gw); err != nil {
+			gw.Close()
+			return err
+		}
+		return gw.Close()
+	}
+	return fmt.Errorf("writing %v compression not supported", c)
+}
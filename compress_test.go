@@ -0,0 +1,104 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// bzip2CNF is "p cnf 2 2\n1 -2 0\n2 0\n" compressed with bzip2.
+var bzip2CNF = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x11, 0xb1,
+	0x1b, 0x96, 0x00, 0x00, 0x0a, 0x59, 0x80, 0x00, 0x10, 0x40, 0x02, 0x70,
+	0x00, 0x09, 0x01, 0x40, 0x00, 0x20, 0x00, 0x22, 0x34, 0xd0, 0x69, 0xa1,
+	0x00, 0x30, 0xcb, 0x8c, 0x21, 0x3d, 0x1b, 0x28, 0x98, 0x6e, 0xbc, 0x5d,
+	0xc9, 0x14, 0xe1, 0x42, 0x40, 0x46, 0xc4, 0x6e, 0x58,
+}
+
+func TestDecompress(t *testing.T) {
+	const cnf = "p cnf 2 2\n1 -2 0\n2 0\n"
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte(cnf))
+	gw.Close()
+
+	tests := []struct {
+		data        []byte
+		compression Compression
+	}{
+		{[]byte(cnf), NoCompression},
+		{[]byte{}, NoCompression},
+		{[]byte("B"), NoCompression},
+		{gz.Bytes(), Gzip},
+		{bzip2CNF, Bzip2},
+		{xzCNF, XZ},
+	}
+	for i, dt := range tests {
+		r, compression, err := Decompress(bytes.NewReader(dt.data))
+		if err != nil {
+			t.Errorf("tests[%d]: %v", i, err)
+			continue
+		}
+		if compression != dt.compression {
+			t.Errorf("tests[%d]: expected %v, got %v", i, dt.compression, compression)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("tests[%d]: %v", i, err)
+		}
+		if expected := string(dt.data); dt.compression != NoCompression {
+			if string(data) != cnf {
+				t.Errorf("tests[%d]: expected %q, got %q", i, cnf, data)
+			}
+		} else if string(data) != expected {
+			t.Errorf("tests[%d]: expected %q, got %q", i, expected, data)
+		}
+	}
+
+	// Readers decompress transparently.
+	formula, variables, err := ReadDIMACS(bytes.NewReader(bzip2CNF))
+	if err != nil || variables != 2 || !reflect.DeepEqual(formula, Formula{{1, -2}, {2}}) {
+		t.Errorf("ReadDIMACS: got %v, %d, %v", formula, variables, err)
+	}
+	if _, _, _, err := ReadQDIMACS(bytes.NewReader(gz.Bytes())); err != nil {
+		t.Errorf("ReadQDIMACS: %v", err)
+	}
+	if _, _, _, err := ReadWCNF(bytes.NewReader(gz.Bytes()[:5])); err == nil {
+		t.Error("ReadWCNF: expected an error for truncated gzip data")
+	}
+}
+
+func TestPrintCompressed(t *testing.T) {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(Formula{{1, -2}, {2, 3}})
+	var plain bytes.Buffer
+	if err := p.Print(&plain); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []Compression{NoCompression, Gzip} {
+		var buf bytes.Buffer
+		if err := p.PrintCompressed(&buf, c); err != nil {
+			t.Fatalf("%v: %v", c, err)
+		}
+		r, compression, err := Decompress(&buf)
+		if err != nil || compression != c {
+			t.Fatalf("%v: got %v, %v", c, compression, err)
+		}
+		if data, _ := ioutil.ReadAll(r); string(data) != plain.String() {
+			t.Errorf("%v: expected %q, got %q", c, plain.String(), data)
+		}
+	}
+	for _, c := range []Compression{Bzip2, XZ, Compression(99)} {
+		if err := p.PrintCompressed(&bytes.Buffer{}, c); err == nil {
+			t.Errorf("%v: expected an error", c)
+		}
+	}
+	if s := Compression(99).String(); s != "Compression(99)" {
+		t.Errorf("Expected Compression(99), got %q", s)
+	}
+}
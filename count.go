@@ -0,0 +1,293 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CountModels returns the number of solutions to formula. Only the variables
+// in projection are counted: two solutions that differ only on other
+// variables, such as auxiliary variables from a Tseitin encoding, count once.
+// The signs of the literals in projection are ignored, and a nil projection
+// means variables one through the largest variable in formula. Projected
+// variables that do not occur in formula can take either value.
+//
+// CountModels is an exact, DPLL-style model counter. It splits the formula
+// into components that share no variables, counts each separately, and caches
+// the counts of components it has seen before. Components with no projected
+// variables are checked for satisfiability with a Pigosat instance. Unlike
+// enumerating solutions with Enumerate, the time CountModels takes need not
+// grow with the number of solutions, but it is still exponential in the size
+// of formula in the worst case.
+func CountModels(formula Formula, projection []Literal) *big.Int {
+	clauses, maxVar, ok := normalizeFormula(formula)
+	c := &modelCounter{projected: make(map[Literal]bool),
+		cache: make(map[string]*big.Int)}
+	if projection == nil {
+		for v := Literal(1); v <= maxVar; v++ {
+			c.projected[v] = true
+		}
+	}
+	for _, lit := range projection {
+		if lit != 0 {
+			c.projected[variableOf(lit)] = true
+		}
+	}
+	if !ok {
+		return new(big.Int)
+	}
+
+	return c.count(clauses, c.projected)
+}
+
+// normalizeFormula returns formula's clauses truncated at their first zero,
+// with their literals sorted and deduplicated and with tautologies removed. It
+// also returns the largest variable in formula. ok is false if formula
+// contains an empty clause.
+func normalizeFormula(formula Formula) (clauses [][]Literal, maxVar Literal, ok bool) {
+	ok = true
+	clauses = make([][]Literal, 0, len(formula))
+clauseLoop:
+	for _, clause := range formula {
+		lits := make([]Literal, 0, len(clause))
+		for _, lit := range clause {
+			if lit == 0 {
+				break
+			}
+			if v := variableOf(lit); v > maxVar {
+				maxVar = v
+			}
+			lits = append(lits, lit)
+		}
+		if len(lits) == 0 {
+			ok = false
+			continue
+		}
+		sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+		out := lits[:1]
+		for _, lit := range lits[1:] {
+			if lit != out[len(out)-1] {
+				out = append(out, lit)
+			}
+		}
+		seen := make(map[Literal]bool, len(out))
+		for _, lit := range out {
+			if seen[-lit] {
+				continue clauseLoop
+			}
+			seen[lit] = true
+		}
+		clauses = append(clauses, out)
+	}
+	return
+}
+
+// variableOf returns the variable of lit.
+func variableOf(lit Literal) Literal {
+	if lit < 0 {
+		return -lit
+	}
+	return lit
+}
+
+// modelCounter holds the state of CountModels.
+type modelCounter struct {
+	projected map[Literal]bool
+	cache     map[string]*big.Int // Counts of components, by componentKey
+}
+
+// projectedVariables returns the projected variables occurring in clauses.
+func (c *modelCounter) projectedVariables(clauses [][]Literal) map[Literal]bool {
+	vars := make(map[Literal]bool)
+	for _, clause := range clauses {
+		for _, lit := range clause {
+			if v := variableOf(lit); c.projected[v] {
+				vars[v] = true
+			}
+		}
+	}
+	return vars
+}
+
+// count returns the number of assignments to the variables in vars that can be
+// extended to a solution of clauses. vars must contain the projected variables
+// occurring in clauses. vars is not modified.
+func (c *modelCounter) count(clauses [][]Literal, vars map[Literal]bool) *big.Int {
+	clauses, assigned, ok := propagateUnits(clauses)
+	if !ok {
+		return new(big.Int)
+	}
+	// Variables that propagation neither assigned nor left in clauses are free.
+	free := len(vars) - len(c.projectedVariables(clauses))
+	for v := range assigned {
+		if vars[v] {
+			free--
+		}
+	}
+	n := big.NewInt(1)
+	for _, component := range components(clauses) {
+		m := c.component(component)
+		if m.Sign() == 0 {
+			return m
+		}
+		n.Mul(n, m)
+	}
+	return n.Lsh(n, uint(free))
+}
+
+// component returns the projected model count of a set of clauses that cannot
+// be split into components.
+func (c *modelCounter) component(clauses [][]Literal) *big.Int {
+	key := componentKey(clauses)
+	if n, ok := c.cache[key]; ok {
+		return new(big.Int).Set(n)
+	}
+
+	// Branch on the projected variable occurring most often.
+	occurrences := make(map[Literal]int)
+	var branch Literal
+	for _, clause := range clauses {
+		for _, lit := range clause {
+			v := variableOf(lit)
+			if !c.projected[v] {
+				continue
+			}
+			occurrences[v]++
+			if n := occurrences[v]; n > occurrences[branch] ||
+				n == occurrences[branch] && v < branch {
+				branch = v
+			}
+		}
+	}
+
+	n := new(big.Int)
+	if branch == 0 {
+		if satisfiable(clauses) {
+			n.SetInt64(1)
+		}
+	} else {
+		vars := c.projectedVariables(clauses)
+		delete(vars, branch)
+		n.Add(c.count(assignLiteral(clauses, branch), vars),
+			c.count(assignLiteral(clauses, -branch), vars))
+	}
+	c.cache[key] = new(big.Int).Set(n)
+	return n
+}
+
+// satisfiable uses Pigosat to decide whether clauses are satisfiable.
+func satisfiable(clauses [][]Literal) bool {
+	p, _ := New(nil)
+	defer p.Delete()
+	formula := make(Formula, len(clauses))
+	for i, clause := range clauses {
+		formula[i] = Clause(clause)
+	}
+	p.Add(formula)
+	_, status := p.Solve()
+	return status == Satisfiable
+}
+
+// assignLiteral returns clauses with lit set true: clauses containing lit are
+// removed and -lit is removed from the rest. clauses is not modified.
+func assignLiteral(clauses [][]Literal, lit Literal) [][]Literal {
+	out := make([][]Literal, 0, len(clauses))
+clauseLoop:
+	for _, clause := range clauses {
+		for i, l := range clause {
+			if l == lit {
+				continue clauseLoop
+			}
+			if l == -lit {
+				reduced := make([]Literal, 0, len(clause)-1)
+				reduced = append(reduced, clause[:i]...)
+				out = append(out, append(reduced, clause[i+1:]...))
+				continue clauseLoop
+			}
+		}
+		out = append(out, clause)
+	}
+	return out
+}
+
+// propagateUnits assigns the literals in unit clauses until none remain. It
+// returns the simplified clauses and the variables it assigned. ok is false if
+// propagation derives the empty clause.
+func propagateUnits(clauses [][]Literal) (out [][]Literal, assigned map[Literal]bool, ok bool) {
+	assigned = make(map[Literal]bool)
+	for {
+		var unit Literal
+		for _, clause := range clauses {
+			if len(clause) == 0 {
+				return nil, nil, false
+			}
+			if len(clause) == 1 {
+				unit = clause[0]
+				break
+			}
+		}
+		if unit == 0 {
+			return clauses, assigned, true
+		}
+		assigned[variableOf(unit)] = true
+		clauses = assignLiteral(clauses, unit)
+	}
+}
+
+// components partitions clauses into sets that share no variables.
+func components(clauses [][]Literal) [][][]Literal {
+	parent := make(map[Literal]Literal)
+	var find func(Literal) Literal
+	find = func(v Literal) Literal {
+		if p, ok := parent[v]; ok && p != v {
+			root := find(p)
+			parent[v] = root
+			return root
+		}
+		parent[v] = v
+		return v
+	}
+	for _, clause := range clauses {
+		root := find(variableOf(clause[0]))
+		for _, lit := range clause[1:] {
+			if r := find(variableOf(lit)); r != root {
+				parent[r] = root
+			}
+		}
+	}
+	index := make(map[Literal]int)
+	var parts [][][]Literal
+	for _, clause := range clauses {
+		root := find(variableOf(clause[0]))
+		i, ok := index[root]
+		if !ok {
+			i = len(parts)
+			index[root] = i
+			parts = append(parts, nil)
+		}
+		parts[i] = append(parts[i], clause)
+	}
+	return parts
+}
+
+// componentKey returns a string identifying a set of sorted clauses
+// regardless of their order.
+func componentKey(clauses [][]Literal) string {
+	strs := make([]string, len(clauses))
+	var b bytes.Buffer
+	for i, clause := range clauses {
+		b.Reset()
+		for _, lit := range clause {
+			b.WriteString(strconv.Itoa(int(lit)))
+			b.WriteByte(' ')
+		}
+		strs[i] = b.String()
+	}
+	sort.Strings(strs)
+	return strings.Join(strs, "0 ")
+}
@@ -0,0 +1,82 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+func TestCountModels(t *testing.T) {
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			expected := int64(countSolutions(ft.formula))
+			if n := CountModels(ft.formula, nil); n.Cmp(big.NewInt(expected)) != 0 {
+				t.Errorf("Expected %d models, got %v", expected, n)
+			}
+		})
+	}
+}
+
+func TestCountModelsProjection(t *testing.T) {
+	// Variable 3 is the Tseitin variable for 1 AND 2, and variable 4 is free.
+	formula := Formula{{-3, 1}, {-3, 2}, {3, -1, -2}, {4, -4}, {5, 6, 7}}
+	projections := [][]Literal{nil, {1, 2}, {-2, 1, 2, 0}, {3}, {1, 2, 3},
+		{8}, {}, {1, 5}, {3, 6, 7}, {4, 5, 6, 7}}
+	for i, projection := range projections {
+		t.Run(fmt.Sprintf("projections[%d]", i), func(t *testing.T) {
+			p, _ := New(nil)
+			p.Add(formula)
+			expected, _ := p.Enumerate(context.Background(), projection, 0,
+				func([]Literal) bool { return true })
+			n := CountModels(formula, projection)
+			if n.Cmp(big.NewInt(int64(expected))) != 0 {
+				t.Errorf("Expected %d models, got %v", expected, n)
+			}
+		})
+	}
+}
+
+func TestCountModelsLarge(t *testing.T) {
+	// 100 independent clauses (2i-1 OR 2i) have 3^100 models.
+	const clauses = 100
+	formula := make(Formula, clauses)
+	for i := range formula {
+		formula[i] = Clause{Literal(2*i + 1), Literal(2*i + 2)}
+	}
+	expected := new(big.Int).Exp(big.NewInt(3), big.NewInt(clauses), nil)
+	if n := CountModels(formula, nil); n.Cmp(expected) != 0 {
+		t.Errorf("Expected %v models, got %v", expected, n)
+	}
+
+	// A chain of implications 1 -> 2 -> ... -> 200 has 201 models, projected
+	// onto the odd variables has 101.
+	formula = formula[:0]
+	var odd []Literal
+	for v := Literal(1); v < 2*clauses; v++ {
+		formula = append(formula, Clause{-v, v + 1})
+		if v%2 == 1 {
+			odd = append(odd, v)
+		}
+	}
+	if n := CountModels(formula, nil); n.Cmp(big.NewInt(2*clauses+1)) != 0 {
+		t.Errorf("Expected %d models, got %v", 2*clauses+1, n)
+	}
+	if n := CountModels(formula, odd); n.Cmp(big.NewInt(clauses+1)) != 0 {
+		t.Errorf("Expected %d projected models, got %v", clauses+1, n)
+	}
+}
+
+func ExampleCountModels() {
+	// Variable 3 is true if and only if variables 1 and 2 are both true.
+	formula := Formula{{1, 2}, {-3, 1}, {-3, 2}, {3, -1, -2}}
+	fmt.Println(CountModels(formula, nil))
+	fmt.Println(CountModels(formula, []Literal{1, 2}))
+	fmt.Println(CountModels(formula, []Literal{1, 2, 4}))
+	// Output:
+	// 3
+	// 3
+	// 6
+}
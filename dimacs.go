@@ -0,0 +1,218 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadDIMACS reads a formula in DIMACS CNF format, such as Print writes, from
+// r. The input must start with a header line "p cnf <variables> <clauses>",
+// optionally preceded by comment lines starting with "c". ReadDIMACS returns
+// an error if the input is malformed, if a literal's variable exceeds the
+// header's variable count, or if the number of clauses differs from the
+// header's clause count. The zero ending the last clause may be omitted, and a
+// line containing only "%" ends the input. If r's data are compressed,
+// ReadDIMACS decompresses them. See Decompress.
+func ReadDIMACS(r io.Reader) (formula Formula, variables int, err error) {
+	formula, variables, _, err = ReadDIMACSProjection(r)
+	return
+}
+
+// ReadDIMACSProjection is like ReadDIMACS, but also returns the variables
+// listed in comment lines of the form "c ind <variable> ... 0", which tools
+// such as ApproxMC use to declare the variables to project solutions onto. See
+// Enumerate and CountModels. If there are no such lines, projection is nil.
+func ReadDIMACSProjection(r io.Reader) (formula Formula, variables int,
+	projection []Literal, err error) {
+	f, variables, projection, err := readDIMACS(r)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return f.Formula(), variables, projection, nil
+}
+
+// ReadDIMACSFlat is like ReadDIMACS, but returns the formula as a FlatFormula,
+// which takes far less memory for large formulas.
+func ReadDIMACSFlat(r io.Reader) (formula *FlatFormula, variables int, err error) {
+	formula, variables, _, err = readDIMACS(r)
+	return
+}
+
+// maxPreallocatedClauses is the most clauses readDIMACS makes room for in
+// advance because of the header's clause count.
+const maxPreallocatedClauses = 1 << 16
+
+// readDIMACS is the body of ReadDIMACSProjection and ReadDIMACSFlat.
+func readDIMACS(r io.Reader) (formula *FlatFormula, variables int,
+	projection []Literal, err error) {
+	if r, _, err = Decompress(r); err != nil {
+		return nil, 0, nil, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	var clauses, line int
+	header := false
+	formula = new(FlatFormula)
+	var clause []Literal      // The clause being read, reusing its memory
+	var projectionLines []int // Line number of each element of projection
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 1 && fields[0] == "c" && fields[1] == "ind" {
+			for _, field := range fields[2:] {
+				v, err := strconv.ParseInt(field, 10, 32)
+				if err != nil || v < 0 {
+					return nil, 0, nil, fmt.Errorf("line %d: invalid variable %q",
+						line, field)
+				}
+				if v == 0 {
+					break
+				}
+				projection = append(projection, Literal(v))
+				projectionLines = append(projectionLines, line)
+			}
+			continue
+		}
+		if strings.HasPrefix(fields[0], "c") {
+			continue
+		}
+		if fields[0] == "%" { // SATLIB benchmarks end this way.
+			break
+		}
+		if fields[0] == "p" {
+			if header {
+				return nil, 0, nil, fmt.Errorf("line %d: second header", line)
+			}
+			if variables, clauses, err = parseHeader(fields, "cnf"); err != nil {
+				return nil, 0, nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			header = true
+			// Don't trust the header with more memory than a modest formula's.
+			if clauses <= maxPreallocatedClauses {
+				formula.Grow(0, clauses)
+			} else {
+				formula.Grow(0, maxPreallocatedClauses)
+			}
+			continue
+		}
+		if !header {
+			return nil, 0, nil, fmt.Errorf("line %d: expected header", line)
+		}
+		for _, field := range fields {
+			lit, err := parseLiteral(field, variables)
+			if err != nil {
+				return nil, 0, nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if lit == 0 {
+				formula.AddClause(clause...)
+				clause = clause[:0]
+				continue
+			}
+			clause = append(clause, lit)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, 0, nil, err
+	}
+	if !header {
+		return nil, 0, nil, fmt.Errorf("missing header")
+	}
+	if len(clause) > 0 {
+		formula.AddClause(clause...)
+	}
+	if formula.Len() != clauses {
+		return nil, 0, nil, fmt.Errorf("header promised %d clauses, but found %d",
+			clauses, formula.Len())
+	}
+	for i, v := range projection {
+		if int(v) > variables {
+			return nil, 0, nil, fmt.Errorf(
+				"line %d: variable %d exceeds the %d variables in the header",
+				projectionLines[i], v, variables)
+		}
+	}
+	return formula, variables, projection, nil
+}
+
+// parseHeader parses the fields of a DIMACS header line "p <format> <m> <n>".
+func parseHeader(fields []string, format string) (m, n int, err error) {
+	if len(fields) != 4 || fields[1] != format {
+		return 0, 0, fmt.Errorf("malformed header %q, expected \"p %s <m> <n>\"",
+			strings.Join(fields, " "), format)
+	}
+	if m, err = strconv.Atoi(fields[2]); err != nil || m < 0 {
+		return 0, 0, fmt.Errorf("invalid variable count %q", fields[2])
+	}
+	if n, err = strconv.Atoi(fields[3]); err != nil || n < 0 {
+		return 0, 0, fmt.Errorf("invalid clause count %q", fields[3])
+	}
+	return m, n, nil
+}
+
+// parseLiteral parses a DIMACS literal whose variable is at most variables.
+func parseLiteral(field string, variables int) (Literal, error) {
+	lit, err := strconv.ParseInt(field, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid literal %q", field)
+	}
+	if lit > int64(variables) || -lit > int64(variables) {
+		return 0, fmt.Errorf("literal %d exceeds the %d variables in the header",
+			lit, variables)
+	}
+	return Literal(lit), nil
+}
+
+// WriteDIMACS writes formula to w in DIMACS CNF format. Unlike Print, which
+// writes the clauses a Pigosat object has stored, WriteDIMACS writes formula
+// exactly as given, except that each clause ends at its first zero. The
+// header's variable count is the largest variable in formula.
+func WriteDIMACS(w io.Writer, formula Formula) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p cnf %d %d\n", maxVariable(formula), len(formula))
+	var buf []byte
+	for _, clause := range formula {
+		buf = appendClause(buf[:0], clause)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// maxVariable returns the largest variable in formula, whose clauses end at
+// their first zero.
+func maxVariable(formula Formula) int {
+	variables := 0
+	for _, clause := range formula {
+		for _, lit := range clause {
+			if lit == 0 {
+				break
+			}
+			if v := int(variableOf(lit)); v > variables {
+				variables = v
+			}
+		}
+	}
+	return variables
+}
+
+// appendClause appends the DIMACS line for clause, which ends at its first
+// zero, to buf.
+func appendClause(buf []byte, clause Clause) []byte {
+	for _, lit := range clause {
+		if lit == 0 {
+			break
+		}
+		buf = strconv.AppendInt(buf, int64(lit), 10)
+		buf = append(buf, ' ')
+	}
+	return append(buf, '0', '\n')
+}
@@ -0,0 +1,155 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadDIMACS(t *testing.T) {
+	// Reading what Print writes gives an equivalent formula.
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			formula, variables, err := ReadDIMACS(strings.NewReader(ft.dimacs))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variables != ft.variables {
+				t.Errorf("Expected %d variables, got %d", ft.variables, variables)
+			}
+			p, _ := New(nil)
+			p.Add(formula)
+			if _, status := p.Solve(); status != ft.status {
+				t.Errorf("Expected status %v, got %v", ft.status, status)
+			}
+		})
+	}
+
+	good := []struct {
+		dimacs    string
+		formula   Formula
+		variables int
+	}{
+		{"p cnf 0 0\n", Formula{}, 0},
+		{"c comment\nc\n\np cnf 3 2\n1 -3 0\n2\n3 0\n", Formula{{1, -3}, {2, 3}}, 3},
+		{"p cnf 2 2\n1 2 0 -1 0", Formula{{1, 2}, {-1}}, 2},
+		{"p cnf 2 2\n1 2 0 -1", Formula{{1, 2}, {-1}}, 2},
+		{"p  cnf 2 2\n0\n\t2 0\n", Formula{nil, {2}}, 2},
+		{"p cnf 5 1\n1 -5 0\n%\n0\n", Formula{{1, -5}}, 5},
+	}
+	for i, dt := range good {
+		formula, variables, err := ReadDIMACS(strings.NewReader(dt.dimacs))
+		if err != nil {
+			t.Errorf("good[%d]: %v", i, err)
+		}
+		if !reflect.DeepEqual(formula, dt.formula) || variables != dt.variables {
+			t.Errorf("good[%d]: expected %v, %d; got %v, %d", i, dt.formula,
+				dt.variables, formula, variables)
+		}
+	}
+
+	bad := []string{
+		"",
+		"1 2 0\n",
+		"p cnf 1 1\np cnf 1 1\n1 0\n",
+		"p cnf 1\n1 0\n",
+		"p dnf 1 1\n1 0\n",
+		"p cnf -1 1\n1 0\n",
+		"p cnf 1 x\n1 0\n",
+		"p cnf 1 1\n2 0\n",
+		"p cnf 1 1\n-2 0\n",
+		"p cnf 1 1\n1 x 0\n",
+		"p cnf 3 1\n1 99999999999 0\n",
+		"p cnf 1 2\n1 0\n",
+		"p cnf 1 1\n1 0 -1 0\n",
+		"p cnf 1 999999999999999\n",
+		"p cnf 1 999999999\n1 0\n",
+	}
+	for i, dimacs := range bad {
+		if _, _, err := ReadDIMACS(strings.NewReader(dimacs)); err == nil {
+			t.Errorf("bad[%d]: expected an error reading %q", i, dimacs)
+		}
+	}
+}
+
+func TestWriteDIMACS(t *testing.T) {
+	var buf bytes.Buffer
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			buf.Reset()
+			if err := WriteDIMACS(&buf, ft.formula); err != nil {
+				t.Fatal(err)
+			}
+			formula, variables, err := ReadDIMACS(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(formula) != len(ft.formula) || variables != ft.variables {
+				t.Errorf("Expected %d clauses and %d variables, got %d and %d",
+					len(ft.formula), ft.variables, len(formula), variables)
+			}
+			for j, clause := range formula {
+				if expected := ft.formula[j]; !reflect.DeepEqual(clause,
+					normalizeClause(expected)) {
+					t.Errorf("Clause %d: expected %v, got %v", j, expected, clause)
+				}
+			}
+		})
+	}
+
+	buf.Reset()
+	if err := WriteDIMACS(&buf, Formula{{1, -2, 0, 3}, {}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "p cnf 2 2\n1 -2 0\n0\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+// normalizeClause returns clause up to its first zero, or nil if empty.
+func normalizeClause(clause Clause) Clause {
+	for i, lit := range clause {
+		if lit == 0 {
+			clause = clause[:i]
+			break
+		}
+	}
+	if len(clause) == 0 {
+		return nil
+	}
+	return clause
+}
+
+func TestReadDIMACSProjection(t *testing.T) {
+	const cnf = "c ind 1 3 0\np cnf 4 1\nc ind 4\nc index 2 0\n1 2 3 4 0\n"
+	formula, variables, projection, err := ReadDIMACSProjection(strings.NewReader(cnf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []Literal{1, 3, 4}; !reflect.DeepEqual(projection, expected) {
+		t.Errorf("Expected projection %v, got %v", expected, projection)
+	}
+	if expected := (Formula{{1, 2, 3, 4}}); !reflect.DeepEqual(formula, expected) || variables != 4 {
+		t.Errorf("Expected %v and 4 variables, got %v and %d", expected,
+			formula, variables)
+	}
+
+	_, _, projection, err = ReadDIMACSProjection(strings.NewReader("p cnf 1 0\n"))
+	if err != nil || projection != nil {
+		t.Errorf("Expected nil projection, got %v, %v", projection, err)
+	}
+
+	for i, cnf := range []string{
+		"p cnf 2 0\nc ind 3 0\n",
+		"p cnf 2 0\nc ind -1 0\n",
+		"p cnf 2 0\nc ind x 0\n",
+	} {
+		if _, _, _, err := ReadDIMACSProjection(strings.NewReader(cnf)); err == nil {
+			t.Errorf("bad[%d]: expected an error reading %q", i, cnf)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+// #include "picosat.h"
+// #include "helpers.h"
+import "C"
+import (
+	"context"
+	"sort"
+	"unsafe"
+)
+
+// Enumerate calls f with each distinct solution of p's formula projected onto
+// the variables in projection. The signs of the literals in projection are
+// ignored, and a nil projection means every variable currently in the
+// formula. Two solutions that differ only on variables outside the projection,
+// such as auxiliary variables from a Tseitin encoding, are reported once.
+//
+// f receives a cube: the literals over the projected variables that are true
+// in the solution, ordered by variable. Enumerate blocks each cube by adding a
+// clause to the formula before calling f, so f may keep the cube and may call
+// p's other methods. If p was created with the SaveOriginalClauses option,
+// Enumerate uses partial models, so a cube may leave out projected variables.
+// Such a cube stands for every assignment to the omitted variables, which
+// makes a single call to f cover many solutions.
+//
+// Enumerate stops after limit cubes unless limit <= 0, when f returns false,
+// or when ctx is done. Cancelling ctx does not interrupt a search already in
+// progress. Enumerate returns the number of cubes passed to f and a status of
+// Unsatisfiable if it found every solution or Unknown if it stopped early.
+//
+// Projected variables that do not yet occur in the formula are added to it.
+// Zeros in projection are ignored. Enumerate panics with a *LiteralError if a
+// literal in projection is math.MinInt32 or its variable exceeds
+// Options.MaxVariable. Use EnumerateChecked to get an error instead.
+// Assumptions made before calling Enumerate apply only to the first solution.
+// See Assume.
+func (p *Pigosat) Enumerate(ctx context.Context, projection []Literal,
+	limit int, f func(cube []Literal) bool) (count int, status Status) {
+	count, status, err := p.EnumerateChecked(ctx, projection, limit, f)
+	if err != nil {
+		panic(err)
+	}
+	return count, status
+}
+
+// EnumerateChecked is like Enumerate, but returns a *LiteralError instead of
+// panicking if a literal in projection is invalid, and returns ErrDeleted
+// instead of panicking if p is uninitialized or deleted when EnumerateChecked
+// begins. If EnumerateChecked returns an error, it does not change the
+// formula or call f.
+func (p *Pigosat) EnumerateChecked(ctx context.Context, projection []Literal,
+	limit int, f func(cube []Literal) bool) (count int, status Status, err error) {
+	vars, err := p.projectionVariables(projection)
+	if err != nil {
+		return 0, Unknown, err
+	}
+	for limit <= 0 || count < limit {
+		if ctx.Err() != nil {
+			return count, Unknown, nil
+		}
+		var cube []Literal
+		if cube, status = p.nextCube(vars); status != Satisfiable {
+			return count, status, nil
+		}
+		count++
+		if !f(cube) {
+			break
+		}
+	}
+	return count, Unknown, nil
+}
+
+// projectionVariables returns the distinct, positive variables in projection
+// in increasing order, or every variable if projection is nil. It makes sure
+// p's formula contains all of them. It returns a *LiteralError, and changes
+// nothing, if a nonzero literal in projection is invalid.
+func (p *Pigosat) projectionVariables(projection []Literal) ([]Literal, error) {
+	unlock, err := p.readyChecked(false)
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+	if projection == nil {
+		n := Literal(C.picosat_variables(p.p))
+		vars := make([]Literal, n)
+		for i := range vars {
+			vars[i] = Literal(i + 1)
+		}
+		return vars, nil
+	}
+	seen := make(map[Literal]bool, len(projection))
+	vars := make([]Literal, 0, len(projection))
+	for _, lit := range projection {
+		if lit == 0 {
+			continue
+		}
+		if err := checkLiteral(lit, p.maxVariable); err != nil {
+			return nil, err
+		}
+		if lit < 0 {
+			lit = -lit
+		}
+		if !seen[lit] {
+			seen[lit] = true
+			vars = append(vars, lit)
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i] < vars[j] })
+	if len(vars) > 0 && int(vars[len(vars)-1]) > int(C.picosat_variables(p.p)) {
+		// void picosat_adjust (PicoSAT *, int max_idx);
+		C.picosat_adjust(p.p, C.int(vars[len(vars)-1]))
+	}
+	return vars, nil
+}
+
+// nextCube solves the formula and, if it is satisfiable, blocks and returns
+// the solution's cube over vars. See Enumerate.
+func (p *Pigosat) nextCube(vars []Literal) (cube []Literal, status Status) {
+	defer p.ready(false)()
+	if status = p.sat(); status != Satisfiable {
+		return
+	}
+	cube = make([]Literal, len(vars))
+	if len(vars) > 0 {
+		partial := C.int(0)
+		if p.saveOriginalClauses {
+			partial = 1
+		}
+		// void pigosat_deref_vars (PicoSAT *, const int * vars, int * lits,
+		//                          int n, int partial);
+		C.pigosat_deref_vars(p.p, (*C.int)(unsafe.Pointer(&vars[0])),
+			(*C.int)(unsafe.Pointer(&cube[0])), C.int(len(vars)), partial)
+	}
+	// Drop unassigned variables, and block the rest.
+	clause := make([]C.int, 0, len(vars)+1)
+	n := 0
+	for _, lit := range cube {
+		if lit != 0 {
+			cube[n] = lit
+			n++
+			clause = append(clause, C.int(-lit))
+		}
+	}
+	cube = cube[:n]
+	clause = append(clause, 0)
+	// int picosat_add_lits (PicoSAT *, int * lits);
+	C.picosat_add_lits(p.p, &clause[0])
+	return
+}
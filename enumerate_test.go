@@ -0,0 +1,231 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// countSolutions counts formula's solutions with a Solve/BlockSolution loop.
+func countSolutions(formula Formula) int {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(formula)
+	count := 0
+	for s, st := p.Solve(); st == Satisfiable; s, st = p.Solve() {
+		count++
+		p.BlockSolution(s)
+	}
+	return count
+}
+
+// expandCube returns every assignment to vars that agrees with cube, each
+// written as a cube itself.
+func expandCube(cube []Literal, vars []Literal) [][]Literal {
+	inCube := make(map[Literal]Literal)
+	for _, lit := range cube {
+		inCube[Literal(abs(lit))] = lit
+	}
+	cubes := [][]Literal{{}}
+	for _, v := range vars {
+		var next [][]Literal
+		for _, c := range cubes {
+			for _, lit := range []Literal{v, -v} {
+				if fixed, ok := inCube[v]; ok && fixed != lit {
+					continue
+				}
+				next = append(next, append(append([]Literal{}, c...), lit))
+			}
+		}
+		cubes = next
+	}
+	return cubes
+}
+
+func TestEnumerate(t *testing.T) {
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			p, _ := New(nil)
+			p.Add(ft.formula)
+			vars := p.Variables()
+			count, status := p.Enumerate(context.Background(), nil, 0,
+				func(cube []Literal) bool {
+					if len(cube) != vars {
+						t.Errorf("Cube %v has %d variables, want %d", cube,
+							len(cube), vars)
+					}
+					return true
+				})
+			if status != Unsatisfiable {
+				t.Errorf("Expected status %v, got %v", Unsatisfiable, status)
+			}
+			if expected := countSolutions(ft.formula); count != expected {
+				t.Errorf("Expected %d solutions, got %d", expected, count)
+			}
+		})
+	}
+}
+
+func TestEnumerateProjection(t *testing.T) {
+	// Variable 3 is the Tseitin variable for 1 AND 2, and variable 4 is free.
+	formula := Formula{{-3, 1}, {-3, 2}, {3, -1, -2}, {4, -4}}
+	tests := []struct {
+		projection []Literal
+		expected   int
+	}{
+		{nil, 8},
+		{[]Literal{1, 2}, 4},
+		{[]Literal{-2, 1, 2, 0}, 4},
+		{[]Literal{3}, 2},
+		{[]Literal{1, 2, 3}, 4},
+		{[]Literal{5}, 2}, // Not yet in the formula
+		{[]Literal{}, 1},
+	}
+	for i, et := range tests {
+		t.Run(fmt.Sprintf("tests[%d]", i), func(t *testing.T) {
+			p, _ := New(nil)
+			p.Add(formula)
+			seen := make(map[string]bool)
+			count, status := p.Enumerate(context.Background(), et.projection, 0,
+				func(cube []Literal) bool {
+					key := fmt.Sprint(cube)
+					if seen[key] {
+						t.Errorf("Duplicate cube %v", cube)
+					}
+					seen[key] = true
+					return true
+				})
+			if count != et.expected || status != Unsatisfiable {
+				t.Errorf("Expected %d, %v; got %d, %v", et.expected,
+					Unsatisfiable, count, status)
+			}
+		})
+	}
+}
+
+func TestEnumeratePartial(t *testing.T) {
+	formula := Formula{{1, 2}, {-3, 1}, {-3, 2}, {3, -1, -2}}
+	vars := []Literal{1, 2}
+	p, _ := New(&Options{SaveOriginalClauses: true})
+	p.Add(formula)
+	seen := make(map[string]bool)
+	count, status := p.Enumerate(context.Background(), vars, 0,
+		func(cube []Literal) bool {
+			for _, c := range expandCube(cube, vars) {
+				key := fmt.Sprint(c)
+				if seen[key] {
+					t.Errorf("Cube %v overlaps another at %v", cube, c)
+				}
+				seen[key] = true
+			}
+			return true
+		})
+	if status != Unsatisfiable {
+		t.Errorf("Expected status %v, got %v", Unsatisfiable, status)
+	}
+	if count > 3 {
+		t.Errorf("Expected at most 3 cubes, got %d", count)
+	}
+	expected := map[string]bool{"[1 2]": true, "[1 -2]": true, "[-1 2]": true}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("Expected cubes to cover %v, got %v", expected, seen)
+	}
+}
+
+func TestEnumerateStops(t *testing.T) {
+	formula := formulaTests[0].formula
+	always := func([]Literal) bool { return true }
+
+	t.Run("limit", func(t *testing.T) {
+		p, _ := New(nil)
+		p.Add(formula)
+		if count, status := p.Enumerate(context.Background(), nil, 3, always); count != 3 || status != Unknown {
+			t.Errorf("Expected 3, %v; got %d, %v", Unknown, count, status)
+		}
+	})
+
+	t.Run("callback", func(t *testing.T) {
+		p, _ := New(nil)
+		p.Add(formula)
+		calls := 0
+		count, status := p.Enumerate(context.Background(), nil, 0,
+			func([]Literal) bool {
+				calls++
+				return calls < 2
+			})
+		if count != 2 || status != Unknown {
+			t.Errorf("Expected 2, %v; got %d, %v", Unknown, count, status)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		p, _ := New(nil)
+		p.Add(formula)
+		ctx, cancel := context.WithCancel(context.Background())
+		count, status := p.Enumerate(ctx, nil, 0, func([]Literal) bool {
+			cancel()
+			return true
+		})
+		if count != 1 || status != Unknown {
+			t.Errorf("Expected 1, %v; got %d, %v", Unknown, count, status)
+		}
+	})
+}
+
+// ExamplePigosat_Enumerate enumerates the distinct assignments to variables 1
+// and 2 that can be extended to a solution of the formula.
+func ExamplePigosat_Enumerate() {
+	p, _ := New(nil)
+	defer p.Delete()
+	// Variable 3 is true if and only if variables 1 and 2 are both true, and
+	// variable 4 is unconstrained.
+	p.Add(Formula{{1, 2}, {-3, 1}, {-3, 2}, {3, -1, -2}, {4, -4}})
+	count, status := p.Enumerate(context.Background(), []Literal{1, 2}, 0,
+		func(cube []Literal) bool {
+			fmt.Println(cube)
+			return true
+		})
+	fmt.Println(count, status)
+	// Output:
+	// [1 2]
+	// [1 -2]
+	// [-1 2]
+	// 3 Unsatisfiable
+}
+
+// TestEnumerateChecked tests that EnumerateChecked rejects invalid projections
+// without changing the formula, and that Enumerate panics on them instead of
+// letting PicoSAT abort.
+func TestEnumerateChecked(t *testing.T) {
+	p, _ := New(&Options{MaxVariable: 10})
+	p.Add(Formula{{1, 2}})
+	called := false
+	f := func([]Literal) bool { called = true; return true }
+	var litErr *LiteralError
+	for _, projection := range [][]Literal{{1, 1 << 28}, {-11}, {math.MinInt32}} {
+		count, status, err := p.EnumerateChecked(context.Background(), projection, 0, f)
+		if !errors.As(err, &litErr) || count != 0 || status != Unknown {
+			t.Errorf("%v: expected a *LiteralError, got %d, %v, %v", projection,
+				count, status, err)
+		}
+		assertPanics(t, "Enumerate", func() {
+			p.Enumerate(context.Background(), projection, 0, f)
+		})
+	}
+	if called || p.Variables() != 2 {
+		t.Errorf("Rejected projections called f or added %d variables", p.Variables())
+	}
+	count, status, err := p.EnumerateChecked(context.Background(), []Literal{10, 0}, 0, f)
+	if count != 2 || status != Unsatisfiable || err != nil {
+		t.Errorf("Expected 2, Unsatisfiable, nil; got %d, %v, %v", count, status, err)
+	}
+	p.Delete()
+	if _, _, err := p.EnumerateChecked(context.Background(), nil, 0, f); !errors.Is(err, ErrDeleted) {
+		t.Errorf("Expected ErrDeleted, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that Pigosat methods return or panic with. Compare errors against
+// them with errors.Is.
+var (
+	// ErrNotUnsatisfiable means a method requiring the Unsatisfiable state,
+	// such as WriteClausalCore, was called when Solve's last status was not
+	// Unsatisfiable or the formula or assumptions have changed since.
+	ErrNotUnsatisfiable = errors.New("expected to be in Unsatisfiable state")
+
+	// ErrNotSatisfiable means a method requiring the Satisfiable state, such
+	// as Value, was called when Solve's last status was not Satisfiable or
+	// the formula or assumptions have changed since.
+	ErrNotSatisfiable = errors.New("expected to be in Satisfiable state")
+
+	// ErrDeleted means a method was called on an uninitialized or deleted
+	// Pigosat object. AddChecked, AssumeChecked, SolveChecked, and
+	// EnumerateChecked return it. Every other method panics with it, even one
+	// that returns an error for other reasons, such as BlockSolution, Print,
+	// or FailedAssumptionsChecked.
+	ErrDeleted = errors.New("attempted to use a deleted Pigosat object")
+
+	// ErrMemoryLimit means PicoSAT's memory exceeds Options.MemoryLimit.
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+
+	// ErrTraceDisabled means a method writing a core or proof trace, such as
+	// WriteClausalCore, was called on a Pigosat object created without
+	// Options.EnableTrace.
+	ErrTraceDisabled = errors.New("trace generation not enabled; set Options.EnableTrace")
+)
+
+// SolutionLengthError means a solution passed to a method such as
+// BlockSolution does not match the number of variables the Pigosat object
+// has. Retrieve it with errors.As.
+type SolutionLengthError struct {
+	Length    int // The length of the solution, including its zeroth element
+	Variables int // The number of variables the Pigosat object has
+}
+
+func (e *SolutionLengthError) Error() string {
+	return fmt.Sprintf("solution length %d, but have %d variables", e.Length,
+		e.Variables)
+}
+
+// LiteralError means a literal passed to a method such as AddChecked is
+// invalid: zero where a zero is not allowed, math.MinInt32, whose negation
+// overflows, or a literal whose variable exceeds Max. Retrieve it with
+// errors.As.
+type LiteralError struct {
+	Literal Literal
+	Max     int // The largest variable allowed
+}
+
+func (e *LiteralError) Error() string {
+	if e.Literal == 0 {
+		return "invalid literal 0"
+	}
+	return fmt.Sprintf("literal %d out of range for variables 1 through %d",
+		e.Literal, e.Max)
+}
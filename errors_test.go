@@ -0,0 +1,101 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestErrTraceDisabled(t *testing.T) {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(Formula{{1}, {-1}})
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected Unsatisfiable, got %v", status)
+	}
+	for name, write := range map[string]func(*bytes.Buffer) error{
+		"WriteClausalCore":   func(b *bytes.Buffer) error { return p.WriteClausalCore(b) },
+		"WriteCompactTrace":  func(b *bytes.Buffer) error { return p.WriteCompactTrace(b) },
+		"WriteExtendedTrace": func(b *bytes.Buffer) error { return p.WriteExtendedTrace(b) },
+	} {
+		if err := write(new(bytes.Buffer)); !errors.Is(err, ErrTraceDisabled) {
+			t.Errorf("%s: expected ErrTraceDisabled, got %v", name, err)
+		}
+	}
+}
+
+func TestErrNotUnsatisfiable(t *testing.T) {
+	p, _ := New(&Options{EnableTrace: true})
+	defer p.Delete()
+	p.Add(Formula{{1}})
+	for i := 0; i < 2; i++ {
+		if err := p.WriteClausalCore(new(bytes.Buffer)); !errors.Is(err, ErrNotUnsatisfiable) {
+			t.Errorf("Expected ErrNotUnsatisfiable, got %v", err)
+		}
+		p.Solve()
+	}
+}
+
+func TestSolutionLengthError(t *testing.T) {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(Formula{{1, 2}})
+	var lengthErr *SolutionLengthError
+	for name, err := range map[string]error{
+		"BlockSolution":    p.BlockSolution(Solution{false, true}),
+		"BlockProjected":   p.BlockProjected(Solution{false, true}, []Literal{1}),
+		"BlockBitSolution": p.BlockBitSolution(NewBitSolution(1)),
+	} {
+		if !errors.As(err, &lengthErr) {
+			t.Errorf("%s: expected a *SolutionLengthError, got %v", name, err)
+		} else if lengthErr.Length != 2 || lengthErr.Variables != 2 {
+			t.Errorf("%s: unexpected %+v", name, lengthErr)
+		}
+	}
+}
+
+func TestErrDeleted(t *testing.T) {
+	var a, b *Pigosat
+	b, _ = New(nil)
+	b.Delete()
+	for name, p := range map[string]*Pigosat{"uninit": a, "deleted": b} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := p.SolveChecked(); !errors.Is(err, ErrDeleted) {
+				t.Errorf("SolveChecked: expected ErrDeleted, got %v", err)
+			}
+			if err := p.AddChecked(Formula{{1}}); !errors.Is(err, ErrDeleted) {
+				t.Errorf("AddChecked: expected ErrDeleted, got %v", err)
+			}
+			if err := p.AssumeChecked(1); !errors.Is(err, ErrDeleted) {
+				t.Errorf("AssumeChecked: expected ErrDeleted, got %v", err)
+			}
+			// Other methods panic, even ones that return errors.
+			for method, f := range map[string]func(){
+				"Solve":                    func() { p.Solve() },
+				"BlockSolution":            func() { p.BlockSolution(nil) },
+				"Print":                    func() { p.Print(ioutil.Discard) },
+				"FailedAssumptionsChecked": func() { p.FailedAssumptionsChecked() },
+			} {
+				func() {
+					defer func() {
+						if err, ok := recover().(error); !ok || !errors.Is(err, ErrDeleted) {
+							t.Errorf("%s: expected to panic with ErrDeleted, got %v",
+								method, err)
+						}
+					}()
+					f()
+				}()
+			}
+		})
+	}
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(Formula{{1}})
+	if solution, status, err := p.SolveChecked(); err != nil || status != Satisfiable ||
+		!solution[1] {
+		t.Errorf("SolveChecked = %v, %v, %v", solution, status, err)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// FlatFormula is a formula stored in one slice of literals, each clause ended
+// by a zero, as PicoSAT and the DIMACS format store formulas. Building a
+// Formula allocates a slice per clause, which for formulas with millions of
+// clauses costs much memory and garbage-collection time. A FlatFormula needs
+// only two allocations however many clauses it has. The zero value is an
+// empty formula ready to use. Use AddClause to build a FlatFormula, Len and
+// Clause to iterate over its clauses, and Pigosat.AddFlat to add it to a
+// Pigosat instance.
+type FlatFormula struct {
+	lits    []Literal // The clauses, each ended by a zero
+	offsets []int     // Index in lits where each clause starts
+}
+
+// NewFlatFormula returns a FlatFormula holding lits, a sequence of clauses
+// each ended by a zero, such as the literals of a DIMACS file. The zero ending
+// the last clause may be omitted. The FlatFormula uses lits's memory rather
+// than copying it unless it must append the last zero, so do not modify lits
+// afterward.
+func NewFlatFormula(lits []Literal) *FlatFormula {
+	if len(lits) > 0 && lits[len(lits)-1] != 0 {
+		lits = append(lits, 0)
+	}
+	f := &FlatFormula{lits: lits}
+	start := 0
+	for i, lit := range lits {
+		if lit == 0 {
+			f.offsets = append(f.offsets, start)
+			start = i + 1
+		}
+	}
+	return f
+}
+
+// Flatten returns a FlatFormula holding the same clauses as formula. Each
+// clause ends at its first zero, as for Pigosat.Add.
+func Flatten(formula Formula) *FlatFormula {
+	n := 0
+	for _, clause := range formula {
+		n += len(clause) + 1
+	}
+	f := new(FlatFormula)
+	f.Grow(n, len(formula))
+	for _, clause := range formula {
+		f.AddClause(clause...)
+	}
+	return f
+}
+
+// Grow makes room for f to hold at least literals more literals, counting the
+// zeros ending clauses, and clauses more clauses without reallocating.
+func (f *FlatFormula) Grow(literals, clauses int) {
+	if literals > cap(f.lits)-len(f.lits) {
+		lits := make([]Literal, len(f.lits), len(f.lits)+literals)
+		copy(lits, f.lits)
+		f.lits = lits
+	}
+	if clauses > cap(f.offsets)-len(f.offsets) {
+		offsets := make([]int, len(f.offsets), len(f.offsets)+clauses)
+		copy(offsets, f.offsets)
+		f.offsets = offsets
+	}
+}
+
+// AddClause appends a clause of the given literals to f, copying them. As for
+// Pigosat.Add, a zero ends the clause early.
+func (f *FlatFormula) AddClause(lits ...Literal) {
+	f.offsets = append(f.offsets, len(f.lits))
+	for _, lit := range lits {
+		if lit == 0 {
+			break
+		}
+		f.lits = append(f.lits, lit)
+	}
+	f.lits = append(f.lits, 0)
+}
+
+// Len returns the number of clauses in f.
+func (f *FlatFormula) Len() int {
+	return len(f.offsets)
+}
+
+// Clause returns f's ith clause without its ending zero, or nil if the clause
+// is empty. The clause shares f's memory, so do not modify it, but appending
+// to it is safe.
+func (f *FlatFormula) Clause(i int) Clause {
+	start, end := f.offsets[i], len(f.lits)-1
+	if i+1 < len(f.offsets) {
+		end = f.offsets[i+1] - 1
+	}
+	if start == end {
+		return nil
+	}
+	return Clause(f.lits[start:end:end])
+}
+
+// Literals returns f's clauses as one slice, each clause ended by a zero. The
+// slice shares f's memory, so do not modify it.
+func (f *FlatFormula) Literals() []Literal {
+	return f.lits
+}
+
+// Formula returns f's clauses as a Formula. The clauses share f's memory, as
+// for Clause.
+func (f *FlatFormula) Formula() Formula {
+	formula := make(Formula, f.Len())
+	for i := range formula {
+		formula[i] = f.Clause(i)
+	}
+	return formula
+}
+
+// WriteDIMACSFlat is like WriteDIMACS, but writes a FlatFormula.
+func WriteDIMACSFlat(w io.Writer, formula *FlatFormula) error {
+	variables := 0
+	for _, lit := range formula.lits {
+		if v := int(variableOf(lit)); v > variables {
+			variables = v
+		}
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p cnf %d %d\n", variables, formula.Len())
+	var buf []byte
+	for i := 0; i < formula.Len(); i++ {
+		buf = appendClause(buf[:0], formula.Clause(i))
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// AddFlat is like Add, but adds a FlatFormula. Because a FlatFormula is
+// already laid out as PicoSAT expects, AddFlat passes it to PicoSAT in one cgo
+// call without copying it. Like Add, AddFlat panics with a *LiteralError if a
+// literal is math.MinInt32 or its variable exceeds Options.MaxVariable.
+func (p *Pigosat) AddFlat(formula *FlatFormula) {
+	defer p.ready(false)()
+	if formula.Len() == 0 {
+		return
+	}
+	for _, lit := range formula.lits {
+		if lit == 0 {
+			continue
+		}
+		if err := checkLiteral(lit, p.maxVariable); err != nil {
+			panic(err)
+		}
+	}
+	p.state = stateReady
+	p.addLits(formula.lits)
+}
@@ -0,0 +1,136 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFlatFormula(t *testing.T) {
+	formula := Formula{{1, -2, 0, 3}, {}, {3}, {0}, {-1, 2, 3}}
+	expected := Formula{{1, -2}, nil, {3}, nil, {-1, 2, 3}}
+	lits := []Literal{1, -2, 0, 0, 3, 0, 0, -1, 2, 3, 0}
+
+	var built FlatFormula
+	for _, clause := range formula {
+		built.AddClause(clause...)
+	}
+	for name, f := range map[string]*FlatFormula{
+		"AddClause":                     &built,
+		"Flatten":                       Flatten(formula),
+		"NewFlatFormula":                NewFlatFormula(lits),
+		"NewFlatFormula without last 0": NewFlatFormula(lits[:len(lits)-1]),
+	} {
+		if f.Len() != len(expected) {
+			t.Errorf("%s: expected %d clauses, got %d", name, len(expected), f.Len())
+			continue
+		}
+		for i := 0; i < f.Len(); i++ {
+			if !reflect.DeepEqual(f.Clause(i), expected[i]) {
+				t.Errorf("%s: clause %d: expected %v, got %v", name, i, expected[i],
+					f.Clause(i))
+			}
+		}
+		if !reflect.DeepEqual(f.Formula(), expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, f.Formula())
+		}
+		if !reflect.DeepEqual(f.Literals(), lits) {
+			t.Errorf("%s: expected literals %v, got %v", name, lits, f.Literals())
+		}
+	}
+
+	// NewFlatFormula does not copy, and appending to a clause is safe.
+	f := NewFlatFormula(lits)
+	if &f.Literals()[0] != &lits[0] {
+		t.Error("NewFlatFormula copied its argument")
+	}
+	_ = append(f.Clause(0), 7)
+	if !reflect.DeepEqual(f.Clause(1), Clause(nil)) || f.Literals()[2] != 0 {
+		t.Errorf("Appending to a clause modified the formula: %v", f.Literals())
+	}
+
+	var empty FlatFormula
+	if empty.Len() != 0 || len(empty.Formula()) != 0 || NewFlatFormula(nil).Len() != 0 {
+		t.Error("Expected empty formulas")
+	}
+	empty.Grow(10, 3)
+	if cap(empty.lits) < 10 || cap(empty.offsets) < 3 {
+		t.Errorf("Grow did not grow: %d, %d", cap(empty.lits), cap(empty.offsets))
+	}
+	first := &empty.lits[:1][0]
+	for i := 0; i < 3; i++ {
+		empty.AddClause(1, 2)
+	}
+	if &empty.lits[0] != first {
+		t.Error("AddClause reallocated after Grow")
+	}
+}
+
+func TestFlatDIMACS(t *testing.T) {
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			var flat, plain bytes.Buffer
+			if err := WriteDIMACSFlat(&flat, Flatten(ft.formula)); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteDIMACS(&plain, ft.formula); err != nil {
+				t.Fatal(err)
+			}
+			if flat.String() != plain.String() {
+				t.Fatalf("Expected %q, got %q", plain.String(), flat.String())
+			}
+			f, variables, err := ReadDIMACSFlat(&flat)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variables != ft.variables || f.Len() != len(ft.formula) {
+				t.Errorf("Expected %d clauses and %d variables, got %d and %d",
+					len(ft.formula), ft.variables, f.Len(), variables)
+			}
+			p, _ := New(nil)
+			defer p.Delete()
+			p.AddFlat(f)
+			solution, status := p.Solve()
+			wasExpected(t, p, &ft, status, solution)
+		})
+	}
+	if _, _, err := ReadDIMACSFlat(strings.NewReader("p cnf 1 1\n2 0\n")); err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestAddFlat(t *testing.T) {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.AddFlat(&FlatFormula{})
+	p.AddFlat(NewFlatFormula([]Literal{1, 2, 0, -1}))
+	if n := p.AddedOriginalClauses(); n != 2 {
+		t.Errorf("Expected 2 clauses, got %d", n)
+	}
+	if solution, status := p.Solve(); status != Satisfiable || solution[1] || !solution[2] {
+		t.Errorf("Expected {2: true}, got %v, %v", solution, status)
+	}
+	p.AddFlat(NewFlatFormula([]Literal{0}))
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Errorf("Expected Unsatisfiable, got %v", status)
+	}
+}
+
+// BenchmarkAddFlatLarge measures how long it takes to add the formula of
+// BenchmarkAddLarge as a FlatFormula.
+func BenchmarkAddFlatLarge(b *testing.B) {
+	b.StopTimer()
+	formula := Flatten(largeFormula(100000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, _ := New(nil)
+		b.StartTimer()
+		p.AddFlat(formula)
+		b.StopTimer()
+		p.Delete()
+	}
+}
@@ -0,0 +1,126 @@
+/* Copyright William Schwartz 2014. See the LICENSE file for more information. */
+
+#include <stdlib.h>
+
+#include "helpers.h"
+#include "_cgo_export.h"
+
+void
+pigosat_add_flat (PicoSAT * ps, const int * lits, size_t n)
+{
+  size_t i;
+  for (i = 0; i < n; i++)
+    picosat_add (ps, lits[i]);
+}
+
+int
+pigosat_deref_all (PicoSAT * ps, unsigned char * vals, int n)
+{
+  int i, val;
+  for (i = 1; i <= n; i++)
+    {
+      val = picosat_deref (ps, i);
+      if (!val)
+        return i;
+      vals[i] = val > 0;
+    }
+  return 0;
+}
+
+void
+pigosat_deref_vars (PicoSAT * ps, const int * vars, int * lits, int n,
+                    int partial)
+{
+  int i, val;
+  for (i = 0; i < n; i++)
+    {
+      val = partial ? picosat_deref_partial (ps, vars[i])
+                    : picosat_deref (ps, vars[i]);
+      lits[i] = val > 0 ? vars[i] : val < 0 ? -vars[i] : 0;
+    }
+}
+
+int
+pigosat_deref_bits (PicoSAT * ps, uint64_t * words, int n)
+{
+  int i, val;
+  for (i = 1; i <= n; i++)
+    {
+      val = picosat_deref (ps, i);
+      if (!val)
+        return i;
+      if (val > 0)
+        words[i / 64] |= (uint64_t) 1 << (i % 64);
+    }
+  return 0;
+}
+
+static void
+account (pigosat_memory * mem, size_t old_bytes, size_t new_bytes)
+{
+  mem->current -= old_bytes;
+  mem->current += new_bytes;
+  if (mem->current > mem->max)
+    mem->max = mem->current;
+}
+
+static void *
+pigosat_malloc_accounted (void * state, size_t bytes)
+{
+  void * res = malloc (bytes);
+  if (res)
+    account (state, 0, bytes);
+  return res;
+}
+
+static void *
+pigosat_realloc_accounted (void * state, void * ptr, size_t old_bytes,
+                           size_t new_bytes)
+{
+  void * res = realloc (ptr, new_bytes);
+  if (res || !new_bytes)
+    account (state, old_bytes, new_bytes);
+  return res;
+}
+
+static void
+pigosat_free_accounted (void * state, void * ptr, size_t bytes)
+{
+  free (ptr);
+  account (state, bytes, 0);
+}
+
+PicoSAT *
+pigosat_minit (pigosat_memory * mem)
+{
+  return picosat_minit (mem, pigosat_malloc_accounted,
+                        pigosat_realloc_accounted, pigosat_free_accounted);
+}
+
+int
+pigosat_memory_exceeded (pigosat_memory * mem)
+{
+  return mem->limit && mem->current > mem->limit;
+}
+
+static int
+pigosat_interrupt (void * state)
+{
+  pigosat_hooks * hooks = state;
+  if (hooks->suspended)
+    return 0;
+  if (pigosat_memory_exceeded (hooks->mem))
+    return 1;
+  if (hooks->progress)
+    return pigosatProgress (hooks->progress,
+                            picosat_propagations (hooks->ps),
+                            picosat_decisions (hooks->ps),
+                            picosat_visits (hooks->ps));
+  return 0;
+}
+
+void
+pigosat_set_hooks (PicoSAT * ps, pigosat_hooks * hooks)
+{
+  picosat_set_interrupt (ps, hooks, pigosat_interrupt);
+}
@@ -0,0 +1,67 @@
+/* Copyright William Schwartz 2014. See the LICENSE file for more information.
+ *
+ * Helpers that let PiGoSAT do in one cgo call what would otherwise take many.
+ */
+
+#ifndef PIGOSAT_HELPERS_H_INCLUDED
+#define PIGOSAT_HELPERS_H_INCLUDED
+
+#include <stddef.h>
+#include <stdint.h>
+#include "picosat.h"
+
+/* Add the n literals at lits to the formula, each zero ending a clause. */
+void pigosat_add_flat (PicoSAT *, const int * lits, size_t n);
+
+/* Set vals[i] to 1 if variable i is true and to 0 otherwise, for i from 1
+ * to n. Return the first variable without a value, or 0 if every variable has
+ * one. */
+int pigosat_deref_all (PicoSAT *, unsigned char * vals, int n);
+
+/* Set lits[i] to vars[i] if that variable is true, to -vars[i] if it is false,
+ * and to 0 if it has no value, for i below n. Use picosat_deref_partial if
+ * partial is nonzero and picosat_deref otherwise. */
+void pigosat_deref_vars (PicoSAT *, const int * vars, int * lits, int n,
+                         int partial);
+
+/* Set bit i of the bitset words to 1 if variable i is true, for i from 1 to n,
+ * where bit i is bit i % 64 of words[i / 64]. The bits must start at 0. Return
+ * the first variable without a value, or 0 if every variable has one. */
+int pigosat_deref_bits (PicoSAT *, uint64_t * words, int n);
+
+/* Memory accounting for a PicoSAT instance. PicoSAT aborts the process if an
+ * allocation fails, so the allocator never refuses memory. Instead, once
+ * current exceeds a nonzero limit, the interrupt pigosat_set_hooks installs
+ * stops the search. */
+typedef struct pigosat_memory
+{
+  size_t limit;                 /* 0 for no limit */
+  size_t current;               /* Bytes allocated now */
+  size_t max;                   /* Most bytes ever allocated at once */
+} pigosat_memory;
+
+/* Like picosat_init, but account for PicoSAT's memory in mem, which must stay
+ * valid until after picosat_reset. */
+PicoSAT * pigosat_minit (pigosat_memory * mem);
+
+/* Return whether mem's limit is nonzero and exceeded. */
+int pigosat_memory_exceeded (pigosat_memory * mem);
+
+/* State for the interrupt pigosat_set_hooks installs. */
+typedef struct pigosat_hooks
+{
+  PicoSAT * ps;
+  pigosat_memory * mem;         /* Interrupt once its limit is exceeded */
+  uintptr_t progress;           /* Key of a Go progress callback, or 0 */
+  int suspended;                /* Nonzero to never interrupt */
+} pigosat_hooks;
+
+/* Install an interrupt that stops picosat_sat once hooks->mem's limit is
+ * exceeded and otherwise, if hooks->progress is nonzero, calls the Go
+ * function pigosatProgress, which returns whether to stop. PicoSAT checks the
+ * interrupt every 1024 decisions. The interrupt does nothing while
+ * hooks->suspended is nonzero. hooks must stay valid until after
+ * picosat_reset. */
+void pigosat_set_hooks (PicoSAT *, pigosat_hooks * hooks);
+
+#endif
@@ -0,0 +1,474 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PBTerm is the term Coefficient*Literal of a pseudo-Boolean expression, in
+// which Literal counts as one if it is true and zero if it is false.
+type PBTerm struct {
+	Coefficient int64
+	Literal     Literal
+}
+
+// pbConstraint is the linear constraint sum(terms) >= degree.
+type pbConstraint struct {
+	terms  []PBTerm
+	degree int64
+}
+
+// ReadOPB reads a pseudo-Boolean problem in the OPB format of the
+// Pseudo-Boolean Competitions from r. It returns the problem's constraints
+// encoded in CNF and the objective function to minimize, which is nil if the
+// input has none. variables is the number of variables in the problem: the
+// count in the "* #variable= <n>" comment on the first line, if any, or else
+// the largest variable in the input. Variable xN becomes Literal N and ~xN
+// becomes -N. The encoding adds auxiliary variables numbered after variables,
+// so to get solutions of the problem, ignore the rest of the variables, and to
+// enumerate or count solutions, project onto variables one through variables.
+//
+// ReadOPB accepts linear constraints with the relational operators ">=", "=",
+// and "<=", each ending with ";", and an objective "min: <terms> ;". It
+// returns an error if the input is malformed, has nonlinear terms, or has a
+// variable exceeding the "#variable=" count, or if the sum of a constraint's
+// coefficients' absolute values overflows an int64. See PBMinimizer and
+// PBSoftClauses for ways to minimize the objective.
+//
+// Each constraint is encoded as a binary decision diagram in the manner of
+// Eén and Sörensson, "Translating Pseudo-Boolean Constraints into SAT," JSAT
+// 2006. The encoding's size can grow quickly with the number of distinct
+// coefficients.
+func ReadOPB(r io.Reader) (formula Formula, objective []PBTerm, variables int, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	var constraints []pbConstraint
+	var statement []string
+	declared, line := -1, 0
+	seenObjective := false
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.HasPrefix(text, "*") {
+			fields := strings.Fields(text)
+			if line == 1 && len(fields) >= 3 && fields[1] == "#variable=" {
+				declared, err = strconv.Atoi(fields[2])
+				if err != nil || declared < 0 {
+					return nil, nil, 0, fmt.Errorf("line 1: invalid variable count %q",
+						fields[2])
+				}
+			}
+			continue
+		}
+		// Split statements at semicolons, which need not be surrounded by spaces.
+		for i, part := range strings.Split(text, ";") {
+			if i > 0 {
+				if len(statement) == 0 {
+					return nil, nil, 0, fmt.Errorf("line %d: empty statement", line)
+				}
+				if statement[0] == "min:" {
+					if seenObjective || len(constraints) > 0 {
+						return nil, nil, 0, fmt.Errorf(
+							"line %d: the objective must come first and only once", line)
+					}
+					seenObjective = true
+					objective, err = parsePBTerms(statement[1:], &variables)
+					if objective == nil {
+						objective = []PBTerm{}
+					}
+					// PBMinimizer's bounds on the objective double its range.
+					if sum, ok := pbSum(objective); err == nil &&
+						(!ok || sum > math.MaxInt64/2) {
+						err = fmt.Errorf("objective coefficients overflow")
+					}
+				} else {
+					var cs []pbConstraint
+					cs, err = parsePBConstraint(statement, &variables)
+					constraints = append(constraints, cs...)
+				}
+				if err != nil {
+					return nil, nil, 0, fmt.Errorf("line %d: %v", line, err)
+				}
+				statement = statement[:0]
+			}
+			statement = append(statement, strings.Fields(part)...)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+	if len(statement) > 0 {
+		return nil, nil, 0, fmt.Errorf("line %d: missing \";\"", line)
+	}
+	if declared >= 0 {
+		if variables > declared {
+			return nil, nil, 0, fmt.Errorf(
+				"variable x%d exceeds the %d variables declared on line 1",
+				variables, declared)
+		}
+		variables = declared
+	}
+
+	formula = Formula{}
+	next := Literal(variables + 1)
+	for _, c := range constraints {
+		formula = append(formula, c.clauses(&next)...)
+	}
+	return formula, objective, variables, nil
+}
+
+// parsePBTerms parses the fields of a linear expression, updating *variables
+// to the largest variable seen.
+func parsePBTerms(fields []string, variables *int) ([]PBTerm, error) {
+	var terms []PBTerm
+	for i := 0; i < len(fields); i += 2 {
+		coef, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coefficient %q", fields[i])
+		}
+		if i+1 == len(fields) {
+			return nil, fmt.Errorf("coefficient %q has no variable", fields[i])
+		}
+		lit, err := parsePBLiteral(fields[i+1])
+		if err != nil {
+			return nil, err
+		}
+		if i+2 < len(fields) {
+			if _, err := parsePBLiteral(fields[i+2]); err == nil {
+				return nil, fmt.Errorf("nonlinear term %s %s %s not supported",
+					fields[i], fields[i+1], fields[i+2])
+			}
+		}
+		if v := int(variableOf(lit)); v > *variables {
+			*variables = v
+		}
+		terms = append(terms, PBTerm{Coefficient: coef, Literal: lit})
+	}
+	return terms, nil
+}
+
+// parsePBLiteral parses a literal "x<n>" or "~x<n>".
+func parsePBLiteral(field string) (Literal, error) {
+	sign := Literal(1)
+	name := field
+	if strings.HasPrefix(name, "~") {
+		sign, name = -1, name[1:]
+	}
+	if !strings.HasPrefix(name, "x") {
+		return 0, fmt.Errorf("invalid variable %q", field)
+	}
+	v, err := strconv.ParseInt(name[1:], 10, 32)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid variable %q", field)
+	}
+	return sign * Literal(v), nil
+}
+
+// parsePBConstraint parses the fields of a constraint, returning it as one or
+// two constraints of the form sum(terms) >= degree.
+func parsePBConstraint(fields []string, variables *int) ([]pbConstraint, error) {
+	op := -1
+	for i, field := range fields {
+		if field == ">=" || field == "=" || field == "<=" {
+			op = i
+			break
+		}
+	}
+	if op < 0 || op != len(fields)-2 {
+		return nil, fmt.Errorf("malformed constraint %q, expected "+
+			"\"<terms> <operator> <degree>\"", strings.Join(fields, " "))
+	}
+	terms, err := parsePBTerms(fields[:op], variables)
+	if err != nil {
+		return nil, err
+	}
+	degree, err := strconv.ParseInt(fields[op+1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid degree %q", fields[op+1])
+	}
+	sum, ok := pbSum(terms)
+	if !ok {
+		return nil, fmt.Errorf("coefficients overflow")
+	}
+	if degree == math.MinInt64 || abs64(degree) > math.MaxInt64-sum {
+		return nil, fmt.Errorf("degree %d overflows", degree)
+	}
+	var cs []pbConstraint
+	if fields[op] != "<=" {
+		cs = append(cs, pbConstraint{terms, degree})
+	}
+	if fields[op] != ">=" {
+		negated := make([]PBTerm, len(terms))
+		for i, t := range terms {
+			negated[i] = PBTerm{-t.Coefficient, t.Literal}
+		}
+		cs = append(cs, pbConstraint{negated, -degree})
+	}
+	return cs, nil
+}
+
+// pbSum returns the sum of the absolute values of terms' coefficients. ok is
+// false if the sum overflows.
+func pbSum(terms []PBTerm) (sum int64, ok bool) {
+	for _, t := range terms {
+		if t.Coefficient == math.MinInt64 || sum > math.MaxInt64-abs64(t.Coefficient) {
+			return 0, false
+		}
+		sum += abs64(t.Coefficient)
+	}
+	return sum, true
+}
+
+// abs64 returns the absolute value of x, which must not be math.MinInt64.
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// clauses encodes c in CNF with auxiliary variables numbered from *next, which
+// clauses advances. The sum of the absolute values of c's coefficients and
+// degree must not overflow.
+func (c pbConstraint) clauses(next *Literal) Formula {
+	// Make every coefficient positive using a*x = a + |a|*(-x) for a < 0, and
+	// merge terms with the same variable.
+	degree := c.degree
+	coefs := make(map[Literal]int64)
+	for _, t := range c.terms {
+		lit, coef := t.Literal, t.Coefficient
+		if coef == 0 {
+			continue
+		}
+		if coef < 0 {
+			lit, coef = -lit, -coef
+			degree += coef
+		}
+		coefs[lit] += coef
+	}
+	terms := make([]PBTerm, 0, len(coefs))
+	for lit, coef := range coefs {
+		if lit < 0 && coefs[-lit] > 0 {
+			continue // Handled with its complement below.
+		}
+		// a*x + b*(-x) = min(a, b) + (a-min)*x + (b-min)*(-x)
+		if other := coefs[-lit]; other > 0 {
+			m := coef
+			if other < m {
+				m = other
+			}
+			degree -= m
+			if coef > m {
+				terms = append(terms, PBTerm{coef - m, lit})
+			}
+			if other > m {
+				terms = append(terms, PBTerm{other - m, -lit})
+			}
+			continue
+		}
+		if coef > 0 {
+			terms = append(terms, PBTerm{coef, lit})
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Coefficient != terms[j].Coefficient {
+			return terms[i].Coefficient > terms[j].Coefficient
+		}
+		return terms[i].Literal < terms[j].Literal
+	})
+
+	// suffix[i] is the largest value terms[i:] can take.
+	suffix := make([]int64, len(terms)+1)
+	for i := len(terms) - 1; i >= 0; i-- {
+		suffix[i] = suffix[i+1] + terms[i].Coefficient
+	}
+	if degree <= 0 {
+		return nil
+	}
+	if degree > suffix[0] {
+		return Formula{{}}
+	}
+	e := &bddEncoder{terms: terms, suffix: suffix, next: next,
+		nodes: make(map[bddNode]Literal)}
+	root := e.encode(0, degree)
+	return append(e.formula, Clause{root})
+}
+
+// bddNode identifies the constraint sum(terms[i:]) >= degree.
+type bddNode struct {
+	i      int
+	degree int64
+}
+
+// bddEncoder encodes a constraint with positive coefficients in decreasing
+// order as a binary decision diagram.
+type bddEncoder struct {
+	terms   []PBTerm
+	suffix  []int64
+	next    *Literal
+	nodes   map[bddNode]Literal
+	formula Formula
+}
+
+// encode returns a variable implying sum(e.terms[i:]) >= degree, which must be
+// positive and at most e.suffix[i].
+func (e *bddEncoder) encode(i int, degree int64) Literal {
+	node := bddNode{i, degree}
+	if v, ok := e.nodes[node]; ok {
+		return v
+	}
+	v := *e.next
+	*e.next++
+	e.nodes[node] = v
+	t := e.terms[i]
+	// If terms[i] is true, the rest must sum to degree - coefficient. That
+	// constraint is weaker than the one if terms[i] is false, so v implies it
+	// either way.
+	if rest := degree - t.Coefficient; rest > 0 {
+		e.formula = append(e.formula, Clause{-v, e.encode(i+1, rest)})
+	}
+	// If terms[i] is false, the rest must sum to degree.
+	if degree > e.suffix[i+1] {
+		e.formula = append(e.formula, Clause{-v, t.Literal})
+	} else {
+		e.formula = append(e.formula, Clause{-v, t.Literal, e.encode(i+1, degree)})
+	}
+	return v
+}
+
+// PBSoftClauses converts the objective of minimizing the sum of objective's
+// terms into the soft clauses of a weighted MaxSAT problem. Each term with a
+// nonzero coefficient becomes one soft clause. The objective's value for a
+// solution is offset plus the total weight of the soft clauses the solution
+// falsifies. See ReadOPB and ReadWCNF.
+func PBSoftClauses(objective []PBTerm) (soft []SoftClause, offset int64) {
+	for _, t := range objective {
+		switch {
+		case t.Coefficient > 0:
+			soft = append(soft, SoftClause{Clause{-t.Literal}, uint64(t.Coefficient)})
+		case t.Coefficient < 0:
+			// a*x = a + |a|*(-x)
+			soft = append(soft, SoftClause{Clause{t.Literal}, uint64(-t.Coefficient)})
+			offset += t.Coefficient
+		}
+	}
+	return soft, offset
+}
+
+// PBValue returns the value of objective under solution. Literals whose
+// variables solution does not cover count as false.
+func PBValue(objective []PBTerm, solution Solution) int64 {
+	var value int64
+	for _, t := range objective {
+		v := variableOf(t.Literal)
+		if int(v) < len(solution) && solution[v] == (t.Literal > 0) {
+			value += t.Coefficient
+		}
+	}
+	return value
+}
+
+// PBMinimizer is a Minimizer that finds the solution of a formula with the
+// smallest value of a pseudo-Boolean objective, such as ReadOPB returns. Use
+// it with Minimize or ParallelMinimize, and then call Best for the solution.
+// IsFeasible may be called concurrently, but RecordSolution may not.
+type PBMinimizer struct {
+	formula   Formula
+	objective []PBTerm
+	options   *Options
+	next      Literal // First variable available for encoding the objective
+	best      Solution
+	value     int64
+}
+
+// NewPBMinimizer returns a PBMinimizer for minimizing objective subject to
+// formula. IsFeasible solves with a new Pigosat instance created with options
+// each time it is called. NewPBMinimizer returns an error if New does. The
+// objective's bounds must fit in an int.
+func NewPBMinimizer(formula Formula, objective []PBTerm, options *Options) (*PBMinimizer, error) {
+	p, err := New(options)
+	if err != nil {
+		return nil, err
+	}
+	p.Delete()
+	next := Literal(maxVariable(formula) + 1)
+	if v := Literal(maxVariable(Formula{objectiveClause(objective)})); v >= next {
+		next = v + 1
+	}
+	return &PBMinimizer{formula: formula, objective: objective, options: options,
+		next: next}, nil
+}
+
+// objectiveClause returns the literals of objective as a clause.
+func objectiveClause(objective []PBTerm) Clause {
+	clause := make(Clause, 0, len(objective))
+	for _, t := range objective {
+		if t.Literal != 0 {
+			clause = append(clause, t.Literal)
+		}
+	}
+	return clause
+}
+
+// LowerBound returns the sum of the objective's negative coefficients.
+func (m *PBMinimizer) LowerBound() int {
+	var sum int64
+	for _, t := range m.objective {
+		if t.Coefficient < 0 {
+			sum += t.Coefficient
+		}
+	}
+	return int(sum)
+}
+
+// UpperBound returns the sum of the objective's positive coefficients.
+func (m *PBMinimizer) UpperBound() int {
+	var sum int64
+	for _, t := range m.objective {
+		if t.Coefficient > 0 {
+			sum += t.Coefficient
+		}
+	}
+	return int(sum)
+}
+
+// IsFeasible solves the formula with the constraint that the objective is at
+// most k.
+func (m *PBMinimizer) IsFeasible(k int) (solution Solution, status Status) {
+	p, err := New(m.options)
+	if err != nil {
+		panic(err) // NewPBMinimizer already created one successfully.
+	}
+	defer p.Delete()
+	p.Add(m.formula)
+	negated := make([]PBTerm, len(m.objective))
+	for i, t := range m.objective {
+		negated[i] = PBTerm{-t.Coefficient, t.Literal}
+	}
+	next := m.next
+	p.Add(pbConstraint{negated, -int64(k)}.clauses(&next))
+	return p.Solve()
+}
+
+// RecordSolution keeps the satisfiable solution with the smallest objective.
+func (m *PBMinimizer) RecordSolution(k int, solution Solution, status Status) {
+	if status != Satisfiable {
+		return
+	}
+	if value := PBValue(m.objective, solution); m.best == nil || value < m.value {
+		m.best, m.value = solution, value
+	}
+}
+
+// Best returns the recorded solution with the smallest objective and the
+// objective's value. ok is false if no solution has been recorded.
+func (m *PBMinimizer) Best() (solution Solution, value int64, ok bool) {
+	return m.best, m.value, m.best != nil
+}
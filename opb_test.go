@@ -0,0 +1,242 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pbAssignments calls f with each assignment to variables one through n.
+func pbAssignments(n int, f func(Solution)) {
+	for bits := 0; bits < 1<<uint(n); bits++ {
+		s := make(Solution, n+1)
+		for v := 1; v <= n; v++ {
+			s[v] = bits&(1<<uint(v-1)) != 0
+		}
+		f(s)
+	}
+}
+
+// pbSatisfies reports whether solution satisfies sum(terms) op degree.
+func pbSatisfies(terms []PBTerm, op string, degree int64, solution Solution) bool {
+	value := PBValue(terms, solution)
+	switch op {
+	case ">=":
+		return value >= degree
+	case "<=":
+		return value <= degree
+	}
+	return value == degree
+}
+
+// pbProjection returns variables one through n.
+func pbProjection(n int) []Literal {
+	projection := make([]Literal, n)
+	for i := range projection {
+		projection[i] = Literal(i + 1)
+	}
+	return projection
+}
+
+func TestReadOPB(t *testing.T) {
+	opb := `* #variable= 4 #constraint= 3
+* A comment
+min: +2 x1 -3 ~x2 ;
++1 x1 +1 x2 +1 x3 >= 2 ;
+-1 x1 +2 ~x3
+  = 1;
+1 x2 +1 x3 <= 1;`
+	formula, objective, variables, err := ReadOPB(strings.NewReader(opb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variables != 4 {
+		t.Errorf("Expected 4 variables, got %d", variables)
+	}
+	if expected := []PBTerm{{2, 1}, {-3, -2}}; !reflect.DeepEqual(objective, expected) {
+		t.Errorf("Expected objective %v, got %v", expected, objective)
+	}
+	var count int64
+	pbAssignments(4, func(s Solution) {
+		if pbSatisfies([]PBTerm{{1, 1}, {1, 2}, {1, 3}}, ">=", 2, s) &&
+			pbSatisfies([]PBTerm{{-1, 1}, {2, -3}}, "=", 1, s) &&
+			pbSatisfies([]PBTerm{{1, 2}, {1, 3}}, "<=", 1, s) {
+			count++
+		}
+	})
+	if n := CountModels(formula, pbProjection(4)); n.Int64() != count {
+		t.Errorf("Expected %d solutions, got %v", count, n)
+	}
+
+	good := []struct {
+		opb       string
+		objective []PBTerm
+		variables int
+		count     int64
+	}{
+		{"", nil, 0, 1},
+		{"min: ;\n", []PBTerm{}, 0, 1},
+		{"+1 x3 >= 0;", nil, 3, 8},
+		{"+1 x1 >= 2;", nil, 1, 0},
+		{"+1 x1 +1 ~x1 = 1; +1 x2 -1 x2 >= 0;", nil, 2, 4},
+		{"+3 x1 -3 ~x1 >= 3;", nil, 1, 1},
+		{"+2 x1 +1 ~x1 >= 2;", nil, 1, 1},
+		{"+9223372036854775806 x1 >= 1;", nil, 1, 1},
+	}
+	for i, ot := range good {
+		formula, objective, variables, err := ReadOPB(strings.NewReader(ot.opb))
+		if err != nil {
+			t.Errorf("good[%d]: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(objective, ot.objective) || variables != ot.variables {
+			t.Errorf("good[%d]: expected %v, %d; got %v, %d", i, ot.objective,
+				ot.variables, objective, variables)
+		}
+		if n := CountModels(formula, pbProjection(variables)); n.Int64() != ot.count {
+			t.Errorf("good[%d]: expected %d solutions, got %v", i, ot.count, n)
+		}
+	}
+
+	bad := []string{
+		"* #variable= x\n+1 x1 >= 1;",
+		"* #variable= 1\n+1 x2 >= 1;",
+		"+1 x1 >= 1",
+		"+1 x1 >= 1;;",
+		"+1 x1 +1 x2 >= 1; min: +1 x1;",
+		"min: +1 x1; min: +1 x1;",
+		"min: +1 x1 x2;",
+		"+1 x1 x2 >= 1;",
+		"+1 >= 1;",
+		"x1 >= 1;",
+		"+1 y1 >= 1;",
+		"+1 x0 >= 1;",
+		"+1 x1 > 1;",
+		"+1 x1 >= 1 2;",
+		"+1 x1 >= x;",
+		"+9223372036854775807 x1 +1 x2 >= 1;",
+		"-9223372036854775808 x1 >= 1;",
+		"+1 x1 >= 9223372036854775807;",
+		"min: +4611686018427387904 x1;",
+	}
+	for i, opb := range bad {
+		if _, _, _, err := ReadOPB(strings.NewReader(opb)); err == nil {
+			t.Errorf("bad[%d]: expected an error reading %q", i, opb)
+		}
+	}
+}
+
+// TestPBEncoding checks the encoding of random constraints against brute
+// force.
+func TestPBEncoding(t *testing.T) {
+	const n = 5
+	rnd := rand.New(rand.NewSource(1))
+	ops := []string{">=", "<=", "="}
+	for i := 0; i < 200; i++ {
+		var terms []PBTerm
+		b := bytes.NewBufferString("* #variable= 5\n")
+		for j := rnd.Intn(6); j >= 0; j-- {
+			lit := Literal(rnd.Intn(n) + 1)
+			if rnd.Intn(2) == 0 {
+				lit = -lit
+			}
+			term := PBTerm{int64(rnd.Intn(11) - 5), lit}
+			terms = append(terms, term)
+			name := fmt.Sprintf("x%d", term.Literal)
+			if term.Literal < 0 {
+				name = fmt.Sprintf("~x%d", -term.Literal)
+			}
+			fmt.Fprintf(b, "%+d %s ", term.Coefficient, name)
+		}
+		op, degree := ops[rnd.Intn(len(ops))], int64(rnd.Intn(15)-7)
+		fmt.Fprintf(b, "%s %d ;", op, degree)
+		formula, _, _, err := ReadOPB(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var count int64
+		pbAssignments(n, func(s Solution) {
+			if pbSatisfies(terms, op, degree, s) {
+				count++
+			}
+		})
+		if got := CountModels(formula, pbProjection(n)); got.Int64() != count {
+			t.Errorf("%v %s %d: expected %d solutions, got %v", terms, op, degree,
+				count, got)
+		}
+	}
+}
+
+func TestPBMinimizer(t *testing.T) {
+	opb := `min: +3 x1 -2 x2 +4 ~x3 +1 x4 -1 ~x4 ;
++1 x1 +1 x2 +1 x3 >= 2 ;
++2 x2 +1 x3 +1 x4 <= 2 ;`
+	formula, objective, variables, err := ReadOPB(strings.NewReader(opb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	optimum := int64(math.MaxInt64)
+	pbAssignments(variables, func(s Solution) {
+		if pbSatisfies([]PBTerm{{1, 1}, {1, 2}, {1, 3}}, ">=", 2, s) &&
+			pbSatisfies([]PBTerm{{2, 2}, {1, 3}, {1, 4}}, "<=", 2, s) {
+			if v := PBValue(objective, s); v < optimum {
+				optimum = v
+			}
+		}
+	})
+
+	m, err := NewPBMinimizer(formula, objective, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi := m.LowerBound(), m.UpperBound(); lo != -3 || hi != 8 {
+		t.Errorf("Expected bounds -3, 8; got %d, %d", lo, hi)
+	}
+	if _, _, ok := m.Best(); ok {
+		t.Error("Best succeeded before minimizing")
+	}
+	min, optimal, feasible := Minimize(m)
+	if int64(min) != optimum || !optimal || !feasible {
+		t.Errorf("Expected %d, true, true; got %d, %t, %t", optimum, min, optimal,
+			feasible)
+	}
+	solution, value, ok := m.Best()
+	if !ok || value != optimum || PBValue(objective, solution) != optimum {
+		t.Errorf("Expected best value %d, got %d, %t", optimum, value, ok)
+	}
+
+	// An infeasible problem.
+	m, _ = NewPBMinimizer(Formula{{1}, {-1}}, objective, nil)
+	if _, _, feasible := Minimize(m); feasible {
+		t.Error("Expected an infeasible problem")
+	}
+}
+
+func TestPBSoftClauses(t *testing.T) {
+	objective := []PBTerm{{3, 1}, {-2, 2}, {0, 3}, {4, -3}, {-1, -1}}
+	soft, offset := PBSoftClauses(objective)
+	if len(soft) != 4 || offset != -3 {
+		t.Errorf("Expected 4 soft clauses and offset -3, got %v, %d", soft, offset)
+	}
+	pbAssignments(3, func(s Solution) {
+		cost := offset
+		for _, sc := range soft {
+			satisfied := false
+			for _, lit := range sc.Clause {
+				satisfied = satisfied || s[variableOf(lit)] == (lit > 0)
+			}
+			if !satisfied {
+				cost += int64(sc.Weight)
+			}
+		}
+		if v := PBValue(objective, s); cost != v {
+			t.Errorf("%v: expected cost %d, got %d", s, v, cost)
+		}
+	})
+}
@@ -3,7 +3,10 @@
 package pigosat
 
 import (
+	"context"
 	"fmt"
+	"runtime"
+	"sort"
 )
 
 // Minimizer allows you to find the lowest integer K such that
@@ -58,3 +61,142 @@ func Minimize(m Minimizer) (min int, optimal, feasible bool) {
 	}
 	return hi, optimal, true
 }
+
+// CancelableMinimizer is a Minimizer whose feasibility checks can be cancelled.
+// ParallelMinimize cancels ctx when another probe's result makes the probe at
+// k moot. IsFeasibleContext should then return promptly with status Unknown,
+// and ParallelMinimize discards its return values. If it finished anyway and
+// returns another status, ParallelMinimize uses the result like any other.
+type CancelableMinimizer interface {
+	Minimizer
+
+	// IsFeasibleContext is like IsFeasible, but should give up when ctx is
+	// done.
+	IsFeasibleContext(ctx context.Context, k int) (solution Solution, status Status)
+}
+
+// probe is the result of one feasibility check in ParallelMinimize.
+type probe struct {
+	k        int
+	solution Solution
+	status   Status
+	moot     bool // Cancelled and gave up with status Unknown.
+}
+
+// ParallelMinimize is like Minimize, but it calls m.IsFeasible concurrently
+// from up to workers goroutines. Instead of bisecting the search interval,
+// each round splits it into workers+1 pieces and probes the boundaries
+// between them all at once. If workers < 1, ParallelMinimize uses
+// runtime.NumCPU() workers. With one worker, ParallelMinimize probes the same
+// values as Minimize.
+//
+// m.IsFeasible must be safe for concurrent use. If m implements
+// CancelableMinimizer, ParallelMinimize calls IsFeasibleContext instead and
+// cancels probes whose answer is already implied by another probe in the same
+// round. Cancelled probes that give up with status Unknown are not passed to
+// m.RecordSolution. Every other probe is passed to m.RecordSolution, which is never called concurrently: m's
+// upper bound first, then the probes of each round in increasing order of k
+// after every probe in that round has returned.
+//
+// The return values and panics are the same as Minimize's.
+func ParallelMinimize(m Minimizer, workers int) (min int, optimal, feasible bool) {
+	hi, lo := m.UpperBound(), m.LowerBound()
+	if hi < lo {
+		panic(fmt.Errorf("UpperBound()=%d < LowerBound()=%d", hi, lo))
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	solution, status := m.IsFeasible(hi)
+	m.RecordSolution(hi, solution, status)
+	if status != Satisfiable {
+		return hi, false, false
+	}
+	for hi > lo {
+		probes := kSection(lo, hi, workers)
+		for _, pr := range runProbes(m, probes) {
+			if pr.moot {
+				continue
+			}
+			m.RecordSolution(pr.k, pr.solution, pr.status)
+			if pr.status == Satisfiable {
+				if pr.k < hi {
+					hi = pr.k
+				}
+			} else {
+				if pr.k+1 > lo {
+					lo = pr.k + 1
+				}
+				optimal = true
+			}
+		}
+	}
+	return hi, optimal, true
+}
+
+// kSection returns up to workers distinct values in [lo, hi) that split the
+// interval into evenly sized pieces. Requires lo < hi.
+func kSection(lo, hi, workers int) []int {
+	n := hi - lo
+	if workers > n {
+		workers = n
+	}
+	ks := make([]int, workers)
+	for i := range ks {
+		// Avoid overflow like in Minimize.
+		ks[i] = lo + int(int64(n)*int64(i+1)/int64(workers+1))
+	}
+	return ks
+}
+
+// runProbes checks the feasibility of each k in ks concurrently and returns
+// the results sorted by k. If m is a CancelableMinimizer, probes made moot by
+// the results of other probes are cancelled, and marked moot if they give up.
+func runProbes(m Minimizer, ks []int) []probe {
+	cm, cancelable := m.(CancelableMinimizer)
+	results := make(chan probe, len(ks))
+	cancels := make(map[int]context.CancelFunc, len(ks))
+	for _, k := range ks {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels[k] = cancel
+		go func(ctx context.Context, k int) {
+			var pr probe
+			pr.k = k
+			if cancelable {
+				pr.solution, pr.status = cm.IsFeasibleContext(ctx, k)
+			} else {
+				pr.solution, pr.status = m.IsFeasible(k)
+			}
+			results <- pr
+		}(ctx, k)
+	}
+
+	// ks is increasing, so a Satisfiable probe at k makes every pending probe
+	// above k moot, and an Unsatisfiable probe at k makes every pending probe
+	// below k moot.
+	probes := make([]probe, 0, len(ks))
+	cancelled := make(map[int]bool, len(ks))
+	for range ks {
+		pr := <-results
+		// A cancelled probe may have finished before it noticed.
+		pr.moot = cancelled[pr.k] && pr.status == Unknown
+		if cancel, ok := cancels[pr.k]; ok {
+			cancel()
+			delete(cancels, pr.k)
+		}
+		probes = append(probes, pr)
+		if pr.moot || !cancelable {
+			continue
+		}
+		for k, cancel := range cancels {
+			if pr.status == Satisfiable && k > pr.k ||
+				pr.status != Satisfiable && k < pr.k {
+				cancel()
+				cancelled[k] = true
+				delete(cancels, k)
+			}
+		}
+	}
+	sort.Slice(probes, func(i, j int) bool { return probes[i].k < probes[j].k })
+	return probes
+}
@@ -1,8 +1,11 @@
 package pigosat
 
 import (
+	"context"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestMinimize will test optimal values from `from` to `to`.
@@ -117,3 +120,160 @@ func TestMinimize(t *testing.T) {
 		assertPanics(t, "Minimize", func() { Minimize(m) })
 	})
 } // func
+
+// parallelMinimizer is a minimizer that is safe for concurrent use.
+type parallelMinimizer struct {
+	t       *testing.T
+	params  parameters
+	lock    sync.Mutex
+	probed  map[int]Status // Every call to IsFeasible
+	records []arguments    // Every call to RecordSolution
+}
+
+func newParallelMinimizer(lo, hi, opt int, t *testing.T) *parallelMinimizer {
+	return &parallelMinimizer{
+		params: parameters{lower: lo, upper: hi, optimal: opt},
+		t:      t,
+		probed: make(map[int]Status),
+	}
+}
+
+func (m *parallelMinimizer) LowerBound() int { return m.params.lower }
+
+func (m *parallelMinimizer) UpperBound() int { return m.params.upper }
+
+func (m *parallelMinimizer) IsFeasible(k int) (solution Solution, status Status) {
+	status = Satisfiable
+	if k < m.params.optimal {
+		status = Unsatisfiable
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.probed[k]; ok {
+		m.t.Errorf("%+v: k=%d probed twice", m.params, k)
+	}
+	m.probed[k] = status
+	return
+}
+
+func (m *parallelMinimizer) RecordSolution(k int, solution Solution, status Status) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.records = append(m.records, arguments{k, status, solution})
+}
+
+// cancelableMinimizer is a parallelMinimizer whose feasible probes take longer
+// the farther they are from the optimum, unless they are cancelled.
+type cancelableMinimizer struct {
+	*parallelMinimizer
+	cancelled map[int]bool
+}
+
+func (m *cancelableMinimizer) IsFeasibleContext(ctx context.Context, k int) (Solution, Status) {
+	if k >= m.params.optimal {
+		select {
+		case <-ctx.Done():
+			m.lock.Lock()
+			m.cancelled[k] = true
+			m.lock.Unlock()
+			return nil, Unknown
+		case <-time.After(time.Duration(k-m.params.optimal) * 10 * time.Millisecond):
+		}
+	}
+	return m.IsFeasible(k)
+}
+
+// checkParallelRecord checks that every probe, except those cancelled, is
+// recorded exactly once with the status IsFeasible returned.
+func checkParallelRecord(t *testing.T, m *parallelMinimizer, cancelled map[int]bool) {
+	seen := make(map[int]bool)
+	for _, arg := range m.records {
+		if seen[arg.k] {
+			t.Errorf("%+v: k=%d recorded twice", m.params, arg.k)
+		}
+		seen[arg.k] = true
+		if cancelled[arg.k] {
+			t.Errorf("%+v: cancelled k=%d recorded", m.params, arg.k)
+		}
+		if status, ok := m.probed[arg.k]; !ok || status != arg.status {
+			t.Errorf("%+v: k=%d recorded as %v, but probed as %v (ok=%v)",
+				m.params, arg.k, arg.status, status, ok)
+		}
+	}
+	for k := range m.probed {
+		if !seen[k] {
+			t.Errorf("%+v: k=%d probed but not recorded", m.params, k)
+		}
+	}
+	if len(m.records) == 0 || m.records[0].k != m.params.upper {
+		t.Errorf("%+v: upper bound not recorded first", m.params)
+	}
+}
+
+// TestParallelMinimize tests ParallelMinimize the same way TestMinimize tests
+// Minimize, with various numbers of workers.
+func TestParallelMinimize(t *testing.T) {
+	for workers := 0; workers <= 4; workers++ {
+		for hi := from / 2; hi <= to/2; hi++ {
+			for lo := from / 2; lo <= hi; lo++ {
+				for opt := lo; opt <= hi+1; opt++ {
+					m := newParallelMinimizer(lo, hi, opt, t)
+					min, optimal, feasible := ParallelMinimize(m, workers)
+					checkParallelRecord(t, m, nil)
+					if opt <= hi && min != opt {
+						t.Errorf("%+v, workers=%d: min=%d", m.params, workers, min)
+					}
+					if opt > lo && opt <= hi && !optimal {
+						t.Errorf("%+v, workers=%d: Should have been optimal", m.params, workers)
+					} else if opt <= lo && optimal {
+						t.Errorf("%+v, workers=%d: Should not have been optimal", m.params, workers)
+					}
+					if opt <= hi != feasible {
+						t.Errorf("%+v, workers=%d: feasible=%v", m.params, workers, feasible)
+					}
+				} // opt
+			} // lo
+		} // hi
+	} // workers
+
+	// With one worker, ParallelMinimize probes the same values as Minimize.
+	t.Run("one worker", func(t *testing.T) {
+		m := newMinimizer(from, to, 7, t)
+		Minimize(m)
+		pm := newParallelMinimizer(from, to, 7, t)
+		ParallelMinimize(pm, 1)
+		var ks, pks []int
+		for i := 3; i < len(m.args); i += 2 { // Skip newMinimizer's padding.
+			ks = append(ks, m.args[i].k)
+		}
+		for _, arg := range pm.records {
+			pks = append(pks, arg.k)
+		}
+		if !reflect.DeepEqual(ks, pks) {
+			t.Errorf("Minimize probed %v, ParallelMinimize probed %v", ks, pks)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		const lo, hi, opt = 0, 40, 5
+		m := &cancelableMinimizer{newParallelMinimizer(lo, hi, opt, t),
+			make(map[int]bool)}
+		start := time.Now()
+		min, optimal, feasible := ParallelMinimize(m, 4)
+		if d := time.Since(start); d > time.Duration(hi-opt)*10*time.Millisecond {
+			t.Errorf("Moot probes were not cancelled: took %v", d)
+		}
+		if min != opt || !optimal || !feasible {
+			t.Errorf("min=%d, optimal=%v, feasible=%v", min, optimal, feasible)
+		}
+		if len(m.cancelled) == 0 {
+			t.Errorf("No probes were cancelled")
+		}
+		checkParallelRecord(t, m.parallelMinimizer, m.cancelled)
+	})
+
+	t.Run("UpperBound < LowerBound", func(t *testing.T) {
+		m := newParallelMinimizer(to, from, to, t)
+		assertPanics(t, "ParallelMinimize", func() { ParallelMinimize(m, 2) })
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+// #include <stdio.h>
+import "C"
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// outputPipe carries what PicoSAT writes to its output file to an io.Writer,
+// a line-oriented callback, or both, for the lifetime of a Pigosat instance.
+// It is the long-lived counterpart of cFileWriterWrapper.
+type outputPipe struct {
+	cfile *C.FILE  // The write end of the pipe, for PicoSAT
+	wp    *os.File // The write end of the pipe, which owns cfile's descriptor
+	rp    *os.File // The read end of the pipe
+	done  chan struct{}
+}
+
+// newOutputPipe returns an outputPipe copying PicoSAT's output to w, if w is
+// not nil, and calling log with each line of it, without the newline, if log
+// is not nil.
+func newOutputPipe(w io.Writer, log func(line string)) (*outputPipe, error) {
+	rp, wp, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cfile, err := cfdopen(wp, "a") // wp.Close() in close closes cfile.
+	if err != nil {
+		wp.Close()
+		rp.Close()
+		return nil, err
+	}
+	// Deliver each line as soon as PicoSAT finishes writing it.
+	// int setvbuf (FILE *stream, char *buf, int mode, size_t size);
+	C.setvbuf(cfile, nil, C._IOLBF, 0)
+	o := &outputPipe{cfile: cfile, wp: wp, rp: rp, done: make(chan struct{})}
+	go o.copy(w, log)
+	return o, nil
+}
+
+// copy reads the pipe until close closes its write end. It keeps reading even
+// if w returns an error, because PicoSAT blocks once the pipe is full.
+func (o *outputPipe) copy(w io.Writer, log func(line string)) {
+	defer close(o.done)
+	var r io.Reader = o.rp
+	if w != nil {
+		r = io.TeeReader(r, &stickyWriter{w: w})
+	}
+	if log != nil {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			log(scanner.Text())
+		}
+	} else {
+		io.Copy(ioutil.Discard, r)
+	}
+	io.Copy(ioutil.Discard, o.rp)
+}
+
+// close flushes PicoSAT's output, waits until w and log have received all of
+// it, and closes the pipe. Call close after PicoSAT no longer writes to it.
+func (o *outputPipe) close() {
+	// Without flushing cfile, the data might get stuck in the C buffer.
+	C.fflush(o.cfile)
+	// We have to close wp before rp or rp won't know the file has ended.
+	o.wp.Close()
+	<-o.done
+	o.rp.Close()
+}
+
+// stickyWriter writes to w until w returns an error, and then discards its
+// input. It never returns an error.
+type stickyWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (s *stickyWriter) Write(b []byte) (int, error) {
+	if s.err == nil {
+		_, s.err = s.w.Write(b)
+	}
+	return len(b), nil
+}
@@ -64,17 +64,21 @@
 // of colors to vertices so that neighboring vertices have different colors) of
 // the graph that uses at most that number of colors. Minimize can then
 // determine the chromatic number of the graph, the minimum number of colors
-// needed for a proper coloring.
+// needed for a proper coloring. If your IsFeasible is safe for concurrent use,
+// ParallelMinimize does the same search with several calls to IsFeasible
+// running at once.
 package pigosat
 
 // #cgo CFLAGS: -DNDEBUG -DTRACE -O3
 // #cgo windows CFLAGS: -DNGETRUSAGE -DNALLSIGNALS
 // #include "picosat.h" /* REMEMBER TO UPDATE PicosatVersion BELOW! */
+// #include "helpers.h"
 import "C"
 import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"runtime"
 	"sync"
@@ -89,6 +93,11 @@ const Version = "1.0.1"
 // PicosatVersion is the version string from the underlying PicoSAT library.
 const PicosatVersion = "965"
 
+// DefaultMaxVariable is the largest variable Add and Assume accept unless
+// Options.MaxVariable says otherwise. PicoSAT takes about 100 bytes per
+// variable, so DefaultMaxVariable variables take about 1.6 GB.
+const DefaultMaxVariable = 1 << 24
+
 // Argument/result types for Pigosat methods.
 
 // Literal represents a variable or its logical negations. We name variables by
@@ -106,7 +115,7 @@ type Formula []Clause
 
 // Solution is a slice of truth values indexed by and corresponding to each
 // variable's ID number (starting at one). The zeroth element has no meaning and
-// is always false.
+// is always false. BitSolution stores solutions more compactly.
 type Solution []bool
 
 // String returns a readable string like "{1:true, 2:false, ...}" for Solution
@@ -154,7 +163,11 @@ func (s Status) String() string {
 // safe for concurrent use.
 //
 // You must not use runtime.SetFinalizer with Pigosat objects. Attempting to
-// call a method on an uninitialized or deleted Pigosat object panics.
+// call a method on an uninitialized or deleted Pigosat object panics with
+// ErrDeleted. The only exceptions are AddChecked, AssumeChecked, SolveChecked,
+// and EnumerateChecked, which return ErrDeleted instead. Methods that return
+// errors for other reasons, such as BlockSolution, Print, and
+// FailedAssumptionsChecked, still panic.
 //
 // Casual users of PiGoSAT need not call the Delete method. More intensive users
 // should consult Delete's documentation.
@@ -162,12 +175,28 @@ type Pigosat struct {
 	// Pointer to the underlying C struct.
 	p    *C.PicoSAT
 	lock sync.RWMutex
-	// This allows us to avoid the crash demonstrated in
-	// TestCrashOnUnsatResetFailedAssumptions. We keep it set to false except
-	// when Solve just returned Unsatisfiable and nothing has happened to render
-	// assumptions invalid (see documentation for Assume). We reset it to false
-	// every time assumptions become invalid.
-	couldHaveFailedAssumptions bool
+	// The state PicoSAT is in, which determines which PicoSAT functions we
+	// may call without PicoSAT aborting the process, as in the crash
+	// demonstrated in TestCrashOnUnsatResetFailedAssumptions.
+	state solverState
+	// Whether PicoSAT saves original clauses, which picosat_deref_partial
+	// requires.
+	saveOriginalClauses bool
+	// Whether trace generation is enabled, without which PicoSAT aborts when
+	// asked for cores or proof traces.
+	traceEnabled bool
+	// The largest variable Add and Assume accept. See Options.MaxVariable.
+	maxVariable Literal
+	// Accounts for PicoSAT's memory. PicoSAT keeps a pointer to it, so it is
+	// allocated in C and freed after PicoSAT.
+	mem *C.pigosat_memory
+	// Carries PicoSAT's output to Options.Output and Options.Log, or nil.
+	output *outputPipe
+	// The state of PicoSAT's interrupt, or nil if there is none. PicoSAT
+	// keeps a pointer to it, so it is allocated in C and freed after PicoSAT.
+	hooks *C.pigosat_hooks
+	// The state Options.Progress needs, or nil.
+	progress *progressState
 }
 
 // Options contains optional settings for the Pigosat constructor. Zero values
@@ -182,11 +211,30 @@ type Options struct {
 	// object.
 	OutputFile *os.File
 
+	// Set Output to send PicoSAT's output to an io.Writer, such as a logger
+	// or a bytes.Buffer, rather than to a file. Set Log to receive the output
+	// one line at a time, without newlines, for example to send it to a
+	// structured logger. You may set both. PicoSAT's output passes through a
+	// pipe to a goroutine, which writes to Output and calls Log, for the
+	// lifetime of the Pigosat object, so Output and Log need not be safe for
+	// concurrent use. PicoSAT waits while Output or Log blocks. After Output
+	// returns an error, it receives no more output, but Log still does.
+	// Delete returns after Output and Log have received all the output.
+	// Setting OutputFile as well as Output or Log is an error.
+	Output io.Writer
+	Log    func(line string)
+
 	// Set verbosity level. A verbosity level of 1 and above prints more and
-	// more detailed progress reports on the output file, set by OutputFile.
+	// more detailed progress reports on the output file, set by OutputFile,
+	// Output, or Log.
 	// Verbose messages are prefixed with the string set by Prefix.
 	Verbosity uint
 
+	// Set Seed to seed the random number generator PicoSAT uses for random
+	// decisions, for example to reproduce a run or to benchmark with several
+	// different seeds.
+	Seed uint32
+
 	// Set the prefix used for printing verbose messages and statistics.
 	// Default is "c ".
 	Prefix string
@@ -201,6 +249,48 @@ type Options struct {
 	// WriteCompactTrace, WriteExtendedTrace, then set this option true. Doing
 	// so may increase memory usage.
 	EnableTrace bool
+
+	// Set SaveOriginalClauses true to let Enumerate use partial models, which
+	// can block many solutions with one clause. Doing so increases memory
+	// usage.
+	SaveOriginalClauses bool
+
+	// Set MaxVariable to a positive value to reject literals whose variables
+	// exceed it. PicoSAT allocates memory for every variable up to the largest
+	// it has seen, so one huge literal can exhaust memory, and PicoSAT aborts
+	// the process if it cannot allocate. AddChecked and AssumeChecked return a
+	// *LiteralError for rejected literals, and Add and Assume panic with one.
+	// The default is DefaultMaxVariable. Values above math.MaxInt32 mean
+	// math.MaxInt32.
+	MaxVariable int
+
+	// Set Progress to a function for PicoSAT to call about every 1024
+	// decisions while solving, such as to update a progress bar. Return true
+	// from Progress to stop solving, in which case Solve returns Unknown.
+	// Progress runs while p is locked, so it must not call p's methods. If
+	// Progress panics, solving stops and the method that was solving panics
+	// with the same value. Solving that needs fewer than 1024 decisions never
+	// calls Progress. PicoSAT cannot stop MinUnsatisfiableAssumptions,
+	// MaxSatisfiableAssumptions, or NextMaxSatisfiableAssumptions early, so
+	// they do not call Progress.
+	Progress func(Progress) (stop bool)
+
+	// Set MemoryLimit to a positive number of bytes to stop solving once
+	// PicoSAT's memory exceeds it. PicoSAT aborts the process if it cannot
+	// allocate memory, so the limit cannot refuse allocations. Instead, Solve
+	// and similar methods return Unknown once the memory exceeds the limit,
+	// SolveChecked and AddChecked return ErrMemoryLimit, and Add continues to
+	// add clauses. PicoSAT checks the limit every 1024 decisions, so the
+	// memory can overshoot the limit. See CurrentBytes and MaxBytes.
+	//
+	// MemoryLimit does not limit the memory that adding clauses and
+	// assumptions takes. In particular, PicoSAT allocates room for every
+	// variable up to the largest it has seen, so one huge literal can make it
+	// abort even with a MemoryLimit; bound literals with MaxVariable instead.
+	// Nor does MemoryLimit stop MinUnsatisfiableAssumptions,
+	// MaxSatisfiableAssumptions, or NextMaxSatisfiableAssumptions, which
+	// PicoSAT cannot stop early.
+	MemoryLimit uint64
 }
 
 // cfdopen returns a C-level FILE*. mode should be as described in fdopen(3).
@@ -222,12 +312,33 @@ func cfdopen(file *os.File, mode string) (*C.FILE, error) {
 }
 
 // New returns a new Pigosat instance, ready to have literals added to it. The
-// error return value need only be checked if the OutputFile option is non-nil.
+// error return value need only be checked if the OutputFile, Output, or Log
+// option is non-nil.
 // If options is nil, New chooses all defaults.
 func New(options *Options) (*Pigosat, error) {
-	// PicoSAT * picosat_init (void);
-	p := C.picosat_init()
+	mem := (*C.pigosat_memory)(C.calloc(1, C.sizeof_pigosat_memory))
+	// PicoSAT * pigosat_minit (pigosat_memory * mem);
+	p := C.pigosat_minit(mem)
+	pgo := &Pigosat{p: p, lock: sync.RWMutex{}, maxVariable: DefaultMaxVariable,
+		mem: mem}
 	if options != nil {
+		if options.MemoryLimit > 0 || options.Progress != nil {
+			mem.limit = C.size_t(options.MemoryLimit)
+			pgo.hooks = (*C.pigosat_hooks)(C.calloc(1, C.sizeof_pigosat_hooks))
+			pgo.hooks.ps, pgo.hooks.mem = p, mem
+			if options.Progress != nil {
+				var key uintptr
+				key, pgo.progress = registerProgress(options.Progress)
+				pgo.hooks.progress = C.uintptr_t(key)
+			}
+			// void pigosat_set_hooks (PicoSAT *, pigosat_hooks * hooks);
+			C.pigosat_set_hooks(p, pgo.hooks)
+		}
+		if options.MaxVariable >= math.MaxInt32 {
+			pgo.maxVariable = math.MaxInt32
+		} else if options.MaxVariable > 0 {
+			pgo.maxVariable = Literal(options.MaxVariable)
+		}
 		if options.PropagationLimit > 0 {
 			// void picosat_set_propagation_limit (PicoSAT *, unsigned long long limit);
 			C.picosat_set_propagation_limit(p, C.ulonglong(options.PropagationLimit))
@@ -235,17 +346,34 @@ func New(options *Options) (*Pigosat, error) {
 		if options.OutputFile != nil {
 			cfile, err := cfdopen(options.OutputFile, "a")
 			if err != nil {
-				C.picosat_reset(p)
+				pgo.Delete()
 				return nil, &os.PathError{Op: "fdopen",
 					Path: options.OutputFile.Name(), Err: err}
 			}
 			// void picosat_set_output (PicoSAT *, FILE *);
 			C.picosat_set_output(p, cfile)
 		}
+		if options.Output != nil || options.Log != nil {
+			var err error
+			if options.OutputFile != nil {
+				err = fmt.Errorf("cannot set OutputFile with Output or Log")
+			} else {
+				pgo.output, err = newOutputPipe(options.Output, options.Log)
+			}
+			if err != nil {
+				pgo.Delete()
+				return nil, err
+			}
+			C.picosat_set_output(p, pgo.output.cfile)
+		}
 		if options.Verbosity > 0 {
 			// void picosat_set_verbosity (PicoSAT *, int new_verbosity_level);
 			C.picosat_set_verbosity(p, C.int(options.Verbosity))
 		}
+		if options.Seed != 0 {
+			// void picosat_set_seed (PicoSAT *, unsigned random_number_generator_seed);
+			C.picosat_set_seed(p, C.unsigned(options.Seed))
+		}
 		if options.Prefix != "" {
 			// void picosat_set_prefix (PicoSAT *, const char *);
 			prefix := C.CString(options.Prefix)
@@ -261,9 +389,14 @@ func New(options *Options) (*Pigosat, error) {
 				// The cgo CFLAGS guarantee trace generation using -DTRACE.
 				panic("trace generation was not enabled in build")
 			}
+			pgo.traceEnabled = true
+		}
+		if options.SaveOriginalClauses {
+			// void picosat_save_original_clauses (PicoSAT *);
+			C.picosat_save_original_clauses(p)
+			pgo.saveOriginalClauses = true
 		}
 	}
-	pgo := &Pigosat{p: p, lock: sync.RWMutex{}}
 	runtime.SetFinalizer(pgo, (*Pigosat).Delete)
 	return pgo, nil
 }
@@ -283,7 +416,17 @@ func (p *Pigosat) Delete() {
 	}
 	// void picosat_reset (PicoSAT *);
 	C.picosat_reset(p.p)
-	p.p = nil
+	C.free(unsafe.Pointer(p.mem))
+	if p.hooks != nil {
+		if p.hooks.progress != 0 {
+			unregisterProgress(uintptr(p.hooks.progress))
+		}
+		C.free(unsafe.Pointer(p.hooks))
+	}
+	if p.output != nil {
+		p.output.close()
+	}
+	p.p, p.mem, p.hooks, p.progress, p.output = nil, nil, nil, nil, nil
 	runtime.SetFinalizer(p, nil)
 }
 
@@ -293,8 +436,26 @@ func (p *Pigosat) Delete() {
 //     defer p.ready(readonly)()
 // where readonly should be true if the method does not write to p and must be
 // false if the method does write to p. If p is uninitialized or deleted,
-// ready panics.
+// ready panics with ErrDeleted.
 func (p *Pigosat) ready(readonly bool) (unlock func()) {
+	unlock, err := p.readyChecked(readonly)
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// readyChecked is like ready, but returns ErrDeleted instead of panicking, in
+// which case it holds no lock and unlock does nothing. Use it like
+//     unlock, err := p.readyChecked(readonly)
+//     defer unlock()
+//     if err != nil {
+//         return err
+//     }
+func (p *Pigosat) readyChecked(readonly bool) (unlock func(), err error) {
+	if p == nil {
+		return func() {}, ErrDeleted
+	}
 	if readonly {
 		p.lock.RLock()
 		unlock = p.lock.RUnlock
@@ -303,10 +464,10 @@ func (p *Pigosat) ready(readonly bool) (unlock func()) {
 		unlock = p.lock.Unlock
 	}
 	if p.p == nil {
-		defer unlock()
-		panic("Attempted to use a deleted Pigosat object")
+		unlock()
+		return func() {}, ErrDeleted
 	}
-	return
+	return unlock, nil
 }
 
 // Variables returns the number of variables in the formula: The m in the DIMACS
@@ -325,6 +486,39 @@ func (p *Pigosat) AddedOriginalClauses() int {
 	return int(C.picosat_added_original_clauses(p.p))
 }
 
+// CurrentBytes returns the number of bytes of memory PicoSAT has allocated.
+func (p *Pigosat) CurrentBytes() uint64 {
+	defer p.ready(true)()
+	return uint64(p.mem.current)
+}
+
+// MaxBytes returns the largest number of bytes of memory PicoSAT has had
+// allocated at once.
+func (p *Pigosat) MaxBytes() uint64 {
+	defer p.ready(true)()
+	return uint64(p.mem.max)
+}
+
+// memoryExceeded returns whether PicoSAT's memory exceeds Options.MemoryLimit.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) memoryExceeded() bool {
+	// int pigosat_memory_exceeded (pigosat_memory * mem);
+	return C.pigosat_memory_exceeded(p.mem) != 0
+}
+
+// suspendHooks keeps the interrupt that Options.Progress and
+// Options.MemoryLimit install from stopping PicoSAT until the returned function
+// is called. PicoSAT aborts the process if the interrupt stops the searches
+// inside picosat_mus_assumptions and the maximal satisfiable subset functions.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) suspendHooks() (resume func()) {
+	if p.hooks == nil {
+		return func() {}
+	}
+	p.hooks.suspended = 1
+	return func() { p.hooks.suspended = 0 }
+}
+
 // Seconds returns the time spent in the PicoSAT library.
 func (p *Pigosat) Seconds() time.Duration {
 	defer p.ready(true)()
@@ -338,26 +532,117 @@ func (p *Pigosat) Seconds() time.Duration {
 //
 // A zero in a clause terminates the clause even if the zero is not at the end
 // of the slice. An empty clause always causes the formula to be unsatisfiable.
+//
+// Add copies the clauses into a buffer of zero-terminated clauses and passes
+// the buffer to PicoSAT in one cgo call, or a few calls for large formulas, to
+// avoid the cost of a cgo call per clause.
+//
+// Add panics with a *LiteralError if a literal is math.MinInt32 or its
+// variable exceeds Options.MaxVariable, rather than let PicoSAT abort the
+// process. Use AddChecked to get an error instead.
 func (p *Pigosat) Add(clauses Formula) {
 	defer p.ready(false)()
-	var count int
+	if err := p.checkFormula(clauses); err != nil {
+		panic(err)
+	}
+	p.add(clauses)
+}
+
+// AddChecked is like Add, but returns a *LiteralError instead of panicking if
+// a literal is math.MinInt32 or its variable exceeds Options.MaxVariable, and
+// returns ErrDeleted instead of panicking if p is uninitialized or deleted. It
+// returns ErrMemoryLimit if PicoSAT's memory already exceeds
+// Options.MemoryLimit. If AddChecked returns an error, it adds none of the
+// clauses.
+func (p *Pigosat) AddChecked(clauses Formula) error {
+	unlock, err := p.readyChecked(false)
+	defer unlock()
+	if err != nil {
+		return err
+	}
+	if err = p.checkFormula(clauses); err != nil {
+		return err
+	}
+	if p.memoryExceeded() {
+		return ErrMemoryLimit
+	}
+	p.add(clauses)
+	return nil
+}
+
+// checkFormula returns a *LiteralError for the first literal in clauses, up to
+// each clause's first zero, that Add must not pass to PicoSAT.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) checkFormula(clauses Formula) error {
 	for _, clause := range clauses {
-		p.couldHaveFailedAssumptions = false
-		count = len(clause)
-		if count == 0 {
-			// int picosat_add (PicoSAT *, int lit);
-			C.picosat_add(p.p, 0)
-			continue
+		for _, lit := range clause {
+			if lit == 0 {
+				break
+			}
+			if err := checkLiteral(lit, p.maxVariable); err != nil {
+				return err
+			}
 		}
-		if clause[count-1] != 0 { // 0 tells PicoSAT where to stop reading array
-			clause = append(clause, 0)
+	}
+	return nil
+}
+
+// checkLiteral returns a *LiteralError if lit is zero, math.MinInt32, or has a
+// variable greater than max.
+func checkLiteral(lit, max Literal) error {
+	if lit == 0 || lit == math.MinInt32 || lit > max || -lit > max {
+		return &LiteralError{Literal: lit, Max: int(max)}
+	}
+	return nil
+}
+
+// add is the body of Add and AddChecked.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) add(clauses Formula) {
+	if len(clauses) == 0 {
+		return
+	}
+	p.state = stateReady
+	size := 0
+	for _, clause := range clauses {
+		if size += len(clause) + 1; size >= addBufferSize {
+			size = addBufferSize
+			break
 		}
-		// int picosat_add_lits (PicoSAT *, int * lits);
-		C.picosat_add_lits(p.p, (*C.int)(&clause[0]))
 	}
+	buf := make([]Literal, 0, size)
+	for _, clause := range clauses {
+		for _, lit := range clause {
+			if lit == 0 {
+				break
+			}
+			buf = append(buf, lit)
+		}
+		buf = append(buf, 0)
+		if len(buf) >= addBufferSize {
+			p.addLits(buf)
+			buf = buf[:0]
+		}
+	}
+	p.addLits(buf)
+}
+
+// addBufferSize is the number of literals after which Add passes its buffer to
+// PicoSAT, bounding the extra memory Add uses for large formulas.
+const addBufferSize = 1 << 16
+
+// addLits adds lits, a sequence of zero-terminated clauses, to the formula.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) addLits(lits []Literal) {
+	if len(lits) == 0 {
+		return
+	}
+	// void pigosat_add_flat (PicoSAT *, const int * lits, size_t n);
+	C.pigosat_add_flat(p.p, (*C.int)(unsafe.Pointer(&lits[0])), C.size_t(len(lits)))
 }
 
-// Print appends the formula in DIMACS format to the given io.Writer.
+// Print appends the formula in DIMACS format to the given io.Writer. To
+// compress the output, use PrintCompressed.
 func (p *Pigosat) Print(w io.Writer) error {
 	defer p.ready(true)()
 	return cFileWriterWrapper(w, func(cfile *C.FILE) error {
@@ -367,21 +652,11 @@ func (p *Pigosat) Print(w io.Writer) error {
 	})
 }
 
-// blocksol adds the inverse of the solution to the clauses.
+// blocksol adds the inverse of the solution to the clauses. See
+// Solution.Clause.
 // This private method does not acquire the lock or check if p is nil.
 func (p *Pigosat) blocksol(sol Solution) {
-	n := C.picosat_variables(p.p)
-	clause := make([]C.int, n+1)
-	for i := C.int(1); i <= n; i++ {
-		if sol[i] {
-			clause[i-1] = -i
-		} else {
-			clause[i-1] = i
-		}
-	}
-	p.couldHaveFailedAssumptions = false
-	// int picosat_add_lits (PicoSAT *, int * lits);
-	C.picosat_add_lits(p.p, &clause[0])
+	p.blockLits(sol.Literals())
 }
 
 // Solve the formula and return the status of the solution: one of the constants
@@ -398,28 +673,89 @@ func (p *Pigosat) blocksol(sol Solution) {
 //        // Do stuff with solution, status
 //        p.BlockSolution(solution)
 //    }
+// Enumerate runs such a loop for you and can ignore auxiliary variables.
 func (p *Pigosat) Solve() (solution Solution, status Status) {
 	defer p.ready(false)()
-	p.couldHaveFailedAssumptions = false
+	return p.solve(nil)
+}
+
+// SolveInto is like Solve, but if buf has enough capacity, SolveInto stores
+// the solution in buf's memory instead of allocating a new Solution. Reusing
+// buf across calls saves an allocation per solution when you solve many times,
+// such as when enumerating solutions. If the status is Satisfiable, the
+// solution is buf resliced to length p.Variables()+1 if buf's capacity allows,
+// or else a new Solution. Otherwise, the solution is nil and buf is unchanged.
+func (p *Pigosat) SolveInto(buf Solution) (solution Solution, status Status) {
+	defer p.ready(false)()
+	return p.solve(buf)
+}
+
+// sat runs PicoSAT's solver and returns its status.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) sat() Status {
+	decisionLimit := C.int(-1)
+	if p.memoryExceeded() {
+		// Only check whether propagation alone solves the formula.
+		decisionLimit = 0
+	}
+	p.progress.begin()
 	// int picosat_sat (PicoSAT *, int decision_limit);
-	status = Status(C.picosat_sat(p.p, -1))
-	if status == Unsatisfiable {
-		p.couldHaveFailedAssumptions = true
-		return
-	} else if status == Unknown {
+	status := Status(C.picosat_sat(p.p, decisionLimit))
+	p.setState(status)
+	p.progress.end()
+	return status
+}
+
+// SolveChecked is like Solve, but returns ErrDeleted instead of panicking if p
+// is uninitialized or deleted, and returns ErrMemoryLimit with status Unknown
+// if PicoSAT's memory exceeds Options.MemoryLimit.
+func (p *Pigosat) SolveChecked() (solution Solution, status Status, err error) {
+	unlock, err := p.readyChecked(false)
+	defer unlock()
+	if err != nil {
+		return nil, Unknown, err
+	}
+	solution, status = p.solve(nil)
+	if status == Unknown && p.memoryExceeded() {
+		err = ErrMemoryLimit
+	}
+	return
+}
+
+// Value returns the value of literal lit in the solution the last call to Solve
+// found. It returns ErrNotSatisfiable if the last call to Solve had status
+// other than Satisfiable or the formula or assumptions have changed since, and
+// a *LiteralError if lit is zero or its variable exceeds p.Variables().
+func (p *Pigosat) Value(lit Literal) (bool, error) {
+	defer p.ready(true)()
+	if p.state != stateSat {
+		return false, ErrNotSatisfiable
+	}
+	if err := checkLiteral(lit, Literal(C.picosat_variables(p.p))); err != nil {
+		return false, err
+	}
+	// int picosat_deref (PicoSAT *, int lit);
+	return C.picosat_deref(p.p, C.int(lit)) > 0, nil
+}
+
+// solve is the body of Solve, SolveInto, and SolveChecked. It fetches the
+// solution from PicoSAT in one cgo call.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) solve(buf Solution) (solution Solution, status Status) {
+	if status = p.sat(); status != Satisfiable {
 		return
-	} else if status != Satisfiable {
-		panic(fmt.Errorf("Unknown sat status: %d", status))
 	}
 	n := int(C.picosat_variables(p.p)) // Calling Pigosat.Variables deadlocks
-	solution = make(Solution, n+1)
-	for i := 1; i <= n; i++ {
-		// int picosat_deref (PicoSAT *, int lit);
-		if val := C.picosat_deref(p.p, C.int(i)); val > 0 {
-			solution[i] = true
-		} else if val == 0 {
-			panic(fmt.Errorf("Variable %d was assigned value 0", i))
-		}
+	if cap(buf) >= n+1 {
+		solution = buf[:n+1]
+	} else {
+		solution = make(Solution, n+1)
+	}
+	solution[0] = false
+	// int pigosat_deref_all (PicoSAT *, unsigned char * vals, int n);
+	if v := C.pigosat_deref_all(p.p, (*C.uchar)(unsafe.Pointer(&solution[0])),
+		C.int(n)); v != 0 {
+		panic(fmt.Errorf("Variable %d was assigned value 0", v))
 	}
 	return
 }
@@ -438,25 +774,82 @@ func (p *Pigosat) res() (status Status) {
 }
 
 // BlockSolution adds a clause to the formula ruling out a given solution. It
-// returns an error if the solution is the wrong length.
+// returns a *SolutionLengthError if the solution is the wrong length. To rule
+// out only some variables' values, use BlockLiterals or BlockProjected.
 func (p *Pigosat) BlockSolution(solution Solution) error {
 	defer p.ready(false)()
 	if n := int(C.picosat_variables(p.p)); len(solution) != n+1 {
-		return fmt.Errorf("solution length %d, but have %d variables",
-			len(solution), n)
+		return &SolutionLengthError{Length: len(solution), Variables: n}
 	}
 	p.blocksol(solution)
 	return nil
 }
 
+// BlockLiterals adds a clause to the formula ruling out every assignment that
+// makes all of lits true, such as a partial solution. It returns a
+// *LiteralError if any literal is zero or its variable exceeds p.Variables().
+// Blocking an empty list of literals adds the empty clause, making the formula
+// unsatisfiable.
+func (p *Pigosat) BlockLiterals(lits []Literal) error {
+	defer p.ready(false)()
+	if err := p.checkLiterals(lits); err != nil {
+		return err
+	}
+	p.blockLits(lits)
+	return nil
+}
+
+// BlockProjected adds a clause to the formula ruling out solution's values of
+// the variables in vars, leaving other variables free. It is BlockLiterals of
+// solution.Restrict(vars), and like BlockSolution, returns a
+// *SolutionLengthError if solution's length is not p.Variables()+1. It also
+// returns an error if any variable in vars is zero or exceeds p.Variables().
+// The signs of the literals in vars are ignored.
+func (p *Pigosat) BlockProjected(solution Solution, vars []Literal) error {
+	defer p.ready(false)()
+	if n := int(C.picosat_variables(p.p)); len(solution) != n+1 {
+		return &SolutionLengthError{Length: len(solution), Variables: n}
+	}
+	if err := p.checkLiterals(vars); err != nil {
+		return err
+	}
+	p.blockLits(solution.Restrict(vars))
+	return nil
+}
+
+// checkLiterals returns a *LiteralError if any of lits is zero or its
+// variable exceeds the number of variables p has.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) checkLiterals(lits []Literal) error {
+	n := Literal(C.picosat_variables(p.p))
+	for _, lit := range lits {
+		if err := checkLiteral(lit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blockLits adds the clause negating each of lits.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) blockLits(lits []Literal) {
+	clause := make([]Literal, len(lits)+1)
+	for i, lit := range lits {
+		clause[i] = -lit
+	}
+	p.state = stateReady
+	p.addLits(clause)
+}
+
 // WriteClausalCore writes in DIMACS format the clauses that were used in
-// deriving the empty clause. Requires that p was created with EnableTrace.
+// deriving the empty clause. Requires that p was created with EnableTrace, or
+// else returns ErrTraceDisabled, and that p is in the Unsatisfiable state, or
+// else returns ErrNotUnsatisfiable.
 func (p *Pigosat) WriteClausalCore(f io.Writer) error {
 	defer p.ready(true)()
-	if Status(C.picosat_res(p.p)) != Unsatisfiable {
-		return fmt.Errorf("expected to be in Unsatisfiable state")
+	if err := p.checkTrace(); err != nil {
+		return err
 	}
-
 	return cFileWriterWrapper(f, func(cfile *C.FILE) error {
 		// void picosat_write_clausal_core (PicoSAT *, FILE * core_file);
 		_, err := C.picosat_write_clausal_core(p.p, cfile)
@@ -464,14 +857,13 @@ func (p *Pigosat) WriteClausalCore(f io.Writer) error {
 	})
 }
 
-// WriteCompactTrace writes a compact proof trace in TraceCheck format. Requires
-// that p was created with EnableTrace.
+// WriteCompactTrace writes a compact proof trace in TraceCheck format. Its
+// requirements are as for WriteClausalCore.
 func (p *Pigosat) WriteCompactTrace(f io.Writer) error {
 	defer p.ready(true)()
-	if Status(C.picosat_res(p.p)) != Unsatisfiable {
-		return fmt.Errorf("expected to be in Unsatisfiable state")
+	if err := p.checkTrace(); err != nil {
+		return err
 	}
-
 	return cFileWriterWrapper(f, func(cfile *C.FILE) error {
 		// void picosat_write_compact_trace (PicoSAT *, FILE * trace_file);
 		_, err := C.picosat_write_compact_trace(p.p, cfile)
@@ -479,14 +871,13 @@ func (p *Pigosat) WriteCompactTrace(f io.Writer) error {
 	})
 }
 
-// WriteExtendedTrace writes an extended proof trace in TraceCheck format.
-// Requires that p was created with EnableTrace.
+// WriteExtendedTrace writes an extended proof trace in TraceCheck format. Its
+// requirements are as for WriteClausalCore.
 func (p *Pigosat) WriteExtendedTrace(f io.Writer) error {
 	defer p.ready(true)()
-	if Status(C.picosat_res(p.p)) != Unsatisfiable {
-		return fmt.Errorf("expected to be in Unsatisfiable state")
+	if err := p.checkTrace(); err != nil {
+		return err
 	}
-
 	return cFileWriterWrapper(f, func(cfile *C.FILE) error {
 		// void picosat_write_extended_trace (PicoSAT *, FILE * trace_file);
 		_, err := C.picosat_write_extended_trace(p.p, cfile)
@@ -494,6 +885,54 @@ func (p *Pigosat) WriteExtendedTrace(f io.Writer) error {
 	})
 }
 
+// CoreLiteral returns whether lit's variable is in the variable core: the
+// variables used in deriving the empty clause. Its requirements are as for
+// WriteClausalCore. It also returns a *LiteralError if lit is zero or
+// math.MinInt32.
+func (p *Pigosat) CoreLiteral(lit Literal) (bool, error) {
+	defer p.ready(false)() // Computes the core.
+	if err := p.checkTrace(); err != nil {
+		return false, err
+	}
+	if err := checkLiteral(lit, math.MaxInt32); err != nil {
+		return false, err
+	}
+	// int picosat_corelit (PicoSAT *, int lit);
+	return C.picosat_corelit(p.p, C.int(lit)) != 0, nil
+}
+
+// CoreClause returns whether the ith clause added to p, counting from zero, is
+// in the clausal core: the clauses used in deriving the empty clause. Clauses
+// that BlockSolution and similar methods add count. Its requirements are as
+// for WriteClausalCore. It also returns an error if i is negative or at least
+// p.AddedOriginalClauses().
+func (p *Pigosat) CoreClause(i int) (bool, error) {
+	defer p.ready(false)() // Computes the core.
+	if err := p.checkTrace(); err != nil {
+		return false, err
+	}
+	// int picosat_added_original_clauses (PicoSAT *);
+	if n := int(C.picosat_added_original_clauses(p.p)); i < 0 || i >= n {
+		return false, fmt.Errorf("clause index %d out of range for %d clauses", i, n)
+	}
+	// int picosat_coreclause (PicoSAT *, int i);
+	return C.picosat_coreclause(p.p, C.int(i)) != 0, nil
+}
+
+// checkTrace returns ErrTraceDisabled if p was created without EnableTrace and
+// ErrNotUnsatisfiable if p is not in the Unsatisfiable state. Writing cores and
+// traces requires both.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) checkTrace() error {
+	if !p.traceEnabled {
+		return ErrTraceDisabled
+	}
+	if p.state != stateUnsat {
+		return ErrNotUnsatisfiable
+	}
+	return nil
+}
+
 // cFileWriterWrapper copies writeFn's data into w. writeFn takes a *C.FILE, and
 // whatever writeFn writes to that *C.FILE, cFileWriterWrapper will then
 // copy to w. This wrapper allows the Go API to write to io.Writers anything
@@ -4,8 +4,12 @@ package pigosat
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"math/rand"
 	"os"
 	"reflect"
 	"runtime"
@@ -235,6 +239,179 @@ func TestFormulas(t *testing.T) {
 	}
 }
 
+// TestAddLarge tests that Add handles formulas longer than its buffer, zeros
+// inside clauses, and empty clauses, and leaves its argument alone.
+func TestAddLarge(t *testing.T) {
+	formula := largeFormula(addBufferSize)
+	clause := make(Clause, 2, 3)
+	clause[0], clause[1] = 1, 2
+	clause[:3][2] = 7
+	formula = append(formula, clause, Clause{-1, 0, 3})
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(formula)
+	if n := p.AddedOriginalClauses(); n != len(formula) {
+		t.Errorf("Expected %d clauses, got %d", len(formula), n)
+	}
+	if clause[:3][2] != 7 {
+		t.Error("Add modified its argument beyond the clause's length")
+	}
+	solution, status := p.Solve()
+	if status != Satisfiable || !evaluate(formula, solution) {
+		t.Errorf("Expected a solution, got %v", status)
+	}
+	p.Add(Formula{{}})
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Errorf("Expected Unsatisfiable after an empty clause, got %v", status)
+	}
+}
+
+// TestAddChecked tests that AddChecked rejects invalid literals without adding
+// any clauses, and that Add panics on them instead of letting PicoSAT abort.
+func TestAddChecked(t *testing.T) {
+	p, _ := New(&Options{MaxVariable: 10})
+	defer p.Delete()
+	var litErr *LiteralError
+	for _, formula := range []Formula{
+		{{1, 2}, {math.MinInt32}},
+		{{1, 11}},
+		{{-11}},
+		{{math.MaxInt32}},
+	} {
+		if err := p.AddChecked(formula); !errors.As(err, &litErr) {
+			t.Errorf("AddChecked(%v): expected a *LiteralError, got %v", formula, err)
+		} else if litErr.Max != 10 {
+			t.Errorf("AddChecked(%v): unexpected %+v", formula, litErr)
+		}
+		assertPanics(t, "Add", func() { p.Add(formula) })
+		assertPanics(t, "AddFlat", func() { p.AddFlat(Flatten(formula)) })
+	}
+	if n := p.AddedOriginalClauses(); n != 0 {
+		t.Errorf("Rejected formulas added %d clauses", n)
+	}
+	// Literals after a clause's first zero are never passed to PicoSAT.
+	if err := p.AddChecked(Formula{{10, -1, 0, 11}, {}}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if n := p.AddedOriginalClauses(); n != 2 {
+		t.Errorf("Expected 2 clauses, got %d", n)
+	}
+
+	q, _ := New(nil)
+	defer q.Delete()
+	if err := q.AddChecked(Formula{{math.MinInt32}}); !errors.As(err, &litErr) {
+		t.Errorf("Expected a *LiteralError for math.MinInt32, got %v", err)
+	}
+	if err := q.AddChecked(Formula{{-1000, 5}}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	// By default, literals too large for PicoSAT to allocate are rejected.
+	for _, lit := range []Literal{DefaultMaxVariable + 1, math.MaxInt32} {
+		err := q.AddChecked(Formula{{lit}})
+		if !errors.As(err, &litErr) || litErr.Max != DefaultMaxVariable {
+			t.Errorf("AddChecked(%d): expected a *LiteralError, got %v", lit, err)
+		}
+	}
+}
+
+// pigeonhole returns the formula that n+1 pigeons fit in n holes, one pigeon
+// per hole. It is unsatisfiable and hard for resolution-based solvers.
+func pigeonhole(n int) Formula {
+	v := func(pigeon, hole int) Literal { return Literal(pigeon*n + hole + 1) }
+	var formula Formula
+	for i := 0; i <= n; i++ {
+		clause := make(Clause, n)
+		for h := range clause {
+			clause[h] = v(i, h)
+		}
+		formula = append(formula, clause)
+	}
+	for h := 0; h < n; h++ {
+		for i := 0; i <= n; i++ {
+			for j := i + 1; j <= n; j++ {
+				formula = append(formula, Clause{-v(i, h), -v(j, h)})
+			}
+		}
+	}
+	return formula
+}
+
+// TestMemoryLimit tests that exceeding Options.MemoryLimit stops Solve and
+// that CurrentBytes and MaxBytes account for PicoSAT's memory.
+func TestMemoryLimit(t *testing.T) {
+	formula := pigeonhole(11)
+	p, _ := New(nil)
+	defer p.Delete()
+	start := p.CurrentBytes()
+	if start == 0 || p.MaxBytes() < start {
+		t.Errorf("CurrentBytes() = %d, MaxBytes() = %d after New", start, p.MaxBytes())
+	}
+	p.Add(formula)
+	added := p.CurrentBytes()
+	if added <= start || p.MaxBytes() < added {
+		t.Errorf("CurrentBytes() = %d, MaxBytes() = %d after Add", added, p.MaxBytes())
+	}
+
+	// The limit stops the search once learned clauses exceed it.
+	q, _ := New(&Options{MemoryLimit: added + 1<<16})
+	defer q.Delete()
+	if err := q.AddChecked(formula); err != nil {
+		t.Fatal(err)
+	}
+	solution, status, err := q.SolveChecked()
+	if status != Unknown || solution != nil || !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("Expected Unknown and ErrMemoryLimit, got %v, %v", status, err)
+	}
+	if q.CurrentBytes() <= added+1<<16 {
+		t.Errorf("Expected more than %d bytes, got %d", added+1<<16, q.CurrentBytes())
+	}
+	if err := q.AddChecked(Formula{{1}}); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("Expected AddChecked to return ErrMemoryLimit, got %v", err)
+	}
+	if _, status := q.Solve(); status != Unknown {
+		t.Errorf("Expected Unknown, got %v", status)
+	}
+
+	// Formulas that propagation alone solves are still solved.
+	r, _ := New(&Options{MemoryLimit: 1})
+	defer r.Delete()
+	r.Add(Formula{{1}, {-1, 2}})
+	if solution, status, err := r.SolveChecked(); status != Satisfiable || err != nil ||
+		!solution[2] {
+		t.Errorf("Expected a solution, got %v, %v, %v", solution, status, err)
+	}
+	r.Add(formula)
+	if _, status := r.Solve(); status != Unknown {
+		t.Errorf("Expected Unknown, got %v", status)
+	}
+
+	// The limit cannot stop the searches inside MinUnsatisfiableAssumptions,
+	// which PicoSAT aborts the process for. The first pass measures the memory
+	// Solve needs, so the second pass's limit is crossed only after Solve.
+	var limit uint64
+	for pass := 0; pass < 2; pass++ {
+		m, _ := New(&Options{MemoryLimit: limit})
+		m.Add(Formula{{-1, -2}})
+		for v := Literal(3); v < 6003; v += 2 {
+			m.Add(Formula{{v, v + 1}})
+		}
+		m.Assume(1)
+		m.Assume(2)
+		if _, status := m.Solve(); status != Unsatisfiable {
+			t.Fatalf("Pass %d: expected Unsatisfiable, got %v", pass, status)
+		}
+		solved := m.MaxBytes()
+		if mus := m.MinUnsatisfiableAssumptions(); len(mus) != 2 {
+			t.Errorf("Pass %d: expected two assumptions, got %v", pass, mus)
+		}
+		if pass == 0 && m.MaxBytes() <= solved {
+			t.Fatal("MinUnsatisfiableAssumptions allocated no memory")
+		}
+		limit = solved
+		m.Delete()
+	}
+}
+
 // TestIterSolveRes tests that Pigosat.Solve works as an iterator and that
 // Pigosat.Res returns Solve's last status.
 func TestIterSolveRes(t *testing.T) {
@@ -273,6 +450,55 @@ func TestIterSolveRes(t *testing.T) {
 	}
 }
 
+// TestSolveInto tests that SolveInto finds the same solutions as Solve and
+// reuses its argument's memory when it can.
+func TestSolveInto(t *testing.T) {
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			p, _ := New(nil)
+			defer p.Delete()
+			q, _ := New(nil)
+			defer q.Delete()
+			p.Add(ft.formula)
+			q.Add(ft.formula)
+			buf := make(Solution, 1, ft.variables+1)
+			for {
+				want, wantStatus := p.Solve()
+				got, status := q.SolveInto(buf)
+				if status != wantStatus {
+					t.Fatalf("SolveInto status %v != Solve status %v", status, wantStatus)
+				}
+				if status != Satisfiable {
+					if got != nil {
+						t.Errorf("Expected nil solution, got %v", got)
+					}
+					break
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("SolveInto = %v != %v = Solve", got, want)
+				}
+				if len(got) <= cap(buf) && &got[0] != &buf[0] {
+					t.Error("SolveInto allocated despite enough capacity")
+				}
+				p.BlockSolution(want)
+				q.BlockSolution(got)
+			}
+		})
+	}
+	// Too little capacity.
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(Formula{{1, 2}, {-3}})
+	buf := make(Solution, 2)
+	solution, status := p.SolveInto(buf)
+	if status != Satisfiable || len(solution) != 4 || !evaluate(Formula{{1, 2}, {-3}}, solution) {
+		t.Errorf("Expected a solution of length 4, got %v, %v", solution, status)
+	}
+	if buf[0] || buf[1] {
+		t.Errorf("SolveInto modified a buffer that was too small: %v", buf)
+	}
+}
+
 func TestBlockSolution(t *testing.T) {
 	var status Status
 	for i, ft := range formulaTests {
@@ -314,6 +540,61 @@ func TestBlockSolution(t *testing.T) {
 	}
 }
 
+// TestBlockProjected tests that enumerating with BlockProjected finds each
+// projected solution once, and that BlockLiterals and BlockProjected validate
+// their arguments.
+func TestBlockProjected(t *testing.T) {
+	// x1 xor x2, with x3 free: two projections onto {1, 2}, four solutions.
+	formula := Formula{{1, 2}, {-1, -2}, {3, -3}}
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(formula)
+	for _, lits := range [][]Literal{{0}, {4}, {-4}, {1, 0}} {
+		if err := p.BlockLiterals(lits); err == nil {
+			t.Errorf("Expected an error blocking %v", lits)
+		}
+	}
+	if err := p.BlockProjected(make(Solution, 3), []Literal{1}); err == nil {
+		t.Error("Expected an error for a short solution")
+	}
+	if err := p.BlockProjected(make(Solution, 4), []Literal{1, 4}); err == nil {
+		t.Error("Expected an error for a variable out of range")
+	}
+	seen := make(map[[2]bool]bool)
+	for solution, status := p.Solve(); status == Satisfiable; solution, status = p.Solve() {
+		key := [2]bool{solution[1], solution[2]}
+		if seen[key] {
+			t.Errorf("Duplicate projection %v", key)
+		}
+		seen[key] = true
+		if err := p.BlockProjected(solution, []Literal{-2, 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(seen) != 2 {
+		t.Errorf("Expected 2 projected solutions, got %d", len(seen))
+	}
+
+	q, _ := New(nil)
+	defer q.Delete()
+	q.Add(formula)
+	if err := q.BlockLiterals([]Literal{1, -2}); err != nil {
+		t.Fatal(err)
+	}
+	for solution, status := q.Solve(); status == Satisfiable; solution, status = q.Solve() {
+		if solution[1] {
+			t.Errorf("Blocked partial solution returned: %v", solution)
+		}
+		q.BlockSolution(solution)
+	}
+	if err := q.BlockLiterals(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, status := q.Solve(); status != Unsatisfiable {
+		t.Errorf("Expected Unsatisfiable after blocking nothing, got %v", status)
+	}
+}
+
 // Also cribbed from Pycosat
 func TestPropLimit(t *testing.T) {
 	for i, ft := range formulaTests {
@@ -405,6 +686,56 @@ func TestOutput(t *testing.T) {
 	})
 }
 
+// errWriter is an io.Writer that always fails.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("errWriter") }
+
+// TestOutputWriter tests Options.Output and Options.Log.
+func TestOutputWriter(t *testing.T) {
+	for i, ft := range formulaTests {
+		t.Run(fmt.Sprintf("formulaTests[%d]", i), func(t *testing.T) {
+			prefix := fmt.Sprintf("asdf%x ", i)
+			var buf bytes.Buffer
+			var lines []string
+			p, err := New(&Options{Verbosity: 1, Output: &buf, Prefix: prefix,
+				Log: func(line string) { lines = append(lines, line) }})
+			if err != nil {
+				t.Fatal(err)
+			}
+			p.Add(ft.formula)
+			p.Solve()
+			p.Delete()
+			if !strings.HasPrefix(buf.String(), prefix) {
+				t.Errorf("Wrong prefix: expected %q, got %q", prefix, buf.String())
+			}
+			expected := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if !reflect.DeepEqual(lines, expected) {
+				t.Errorf("Log received %q, but Output received %q", lines, expected)
+			}
+		})
+	}
+
+	// Errors from Output must not block PicoSAT or stop Log.
+	var lines int
+	p, err := New(&Options{Verbosity: 2, Output: errWriter{},
+		Log: func(string) { lines++ }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Add(pigeonhole(8))
+	p.Solve()
+	p.Delete()
+	if lines == 0 {
+		t.Error("Log received no lines after Output's error")
+	}
+
+	if p, err := New(&Options{Output: &bytes.Buffer{}, OutputFile: os.Stdout}); p != nil ||
+		err == nil {
+		t.Error("Expected New to fail with both Output and OutputFile")
+	}
+}
+
 // Without MeasureAllCalls, AddClasuses is not measured. With it, it is.
 func TestMeasureAllCalls(t *testing.T) {
 	for i, ft := range formulaTests {
@@ -444,16 +775,38 @@ func TestUninitializedOrDeleted(t *testing.T) {
 	for name, p := range map[string]*Pigosat{"uninit": a, "deleted": b} {
 		t.Run(name, func(t *testing.T) {
 			assertPanics(t, "Add", func() { p.Add(Formula{{1}, {2}}) })
+			assertPanics(t, "AddFlat", func() { p.AddFlat(&FlatFormula{}) })
 			assertPanics(t, "Variables", func() { p.Variables() })
 			assertPanics(t, "AddedOriginalClauses", func() {
 				p.AddedOriginalClauses()
 			})
 			assertPanics(t, "Seconds", func() { p.Seconds() })
+			assertPanics(t, "CurrentBytes", func() { p.CurrentBytes() })
+			assertPanics(t, "MaxBytes", func() { p.MaxBytes() })
 			assertPanics(t, "Solve", func() { p.Solve() })
+			assertPanics(t, "SolveInto", func() { p.SolveInto(nil) })
+			assertPanics(t, "SolveBits", func() { p.SolveBits(BitSolution{}) })
+			assertPanics(t, "Value", func() { p.Value(1) })
+			assertPanics(t, "CoreLiteral", func() { p.CoreLiteral(1) })
+			assertPanics(t, "CoreClause", func() { p.CoreClause(0) })
 			assertPanics(t, "BlockSolution", func() {
 				p.BlockSolution(Solution{})
 			})
+			assertPanics(t, "BlockLiterals", func() { p.BlockLiterals(nil) })
+			assertPanics(t, "BlockProjected", func() {
+				p.BlockProjected(Solution{}, nil)
+			})
+			assertPanics(t, "BlockBitSolution", func() {
+				p.BlockBitSolution(BitSolution{})
+			})
+			assertPanics(t, "Backbone", func() { p.Backbone(nil) })
+			assertPanics(t, "Enumerate", func() {
+				p.Enumerate(context.Background(), nil, 0, nil)
+			})
 			assertPanics(t, "Print", func() { p.Print(nil) })
+			assertPanics(t, "PrintCompressed", func() {
+				p.PrintCompressed(&bytes.Buffer{}, Gzip)
+			})
 			assertPanics(t, "Res", func() { p.Res() })
 			assertPanics(t, "WriteClausalCore", func() {
 				var buf *bytes.Buffer
@@ -475,6 +828,15 @@ func TestUninitializedOrDeleted(t *testing.T) {
 			assertPanics(t, "FailedAssumptions", func() {
 				p.FailedAssumptions()
 			})
+			assertPanics(t, "FailedAssumptionsChecked", func() {
+				p.FailedAssumptionsChecked()
+			})
+			assertPanics(t, "MinUnsatisfiableAssumptionsChecked", func() {
+				p.MinUnsatisfiableAssumptionsChecked()
+			})
+			assertPanics(t, "MinUnsatisfiableAssumptions", func() {
+				p.MinUnsatisfiableAssumptions()
+			})
 			assertPanics(t, "MaxSatisfiableAssumptions", func() {
 				p.MaxSatisfiableAssumptions()
 			})
@@ -702,6 +1064,36 @@ func BenchmarkSolve(b *testing.B) {
 	}
 }
 
+// BenchmarkSolveInto measures how long it takes to enumerate solutions with
+// SolveInto reusing one buffer. Compare with BenchmarkIterSolve.
+func BenchmarkSolveInto(b *testing.B) {
+	formula := formulaTests[benchTest].formula
+	var buf Solution
+	for i := 0; i < b.N; i++ {
+		p, _ := New(nil)
+		p.Add(formula)
+		for s, st := p.SolveInto(buf); st == Satisfiable; s, st = p.SolveInto(buf) {
+			buf = s
+			p.BlockSolution(s)
+		}
+		p.Delete()
+	}
+}
+
+// BenchmarkIterSolve measures how long it takes to enumerate solutions with
+// Solve.
+func BenchmarkIterSolve(b *testing.B) {
+	formula := formulaTests[benchTest].formula
+	for i := 0; i < b.N; i++ {
+		p, _ := New(nil)
+		p.Add(formula)
+		for s, st := p.Solve(); st == Satisfiable; s, st = p.Solve() {
+			p.BlockSolution(s)
+		}
+		p.Delete()
+	}
+}
+
 // BenchmarkCreate measures how long it takes just to create a new Pigosat
 // object without any options.
 func BenchmarkCreate(b *testing.B) {
@@ -728,6 +1120,57 @@ func BenchmarkAdd(b *testing.B) {
 	}
 }
 
+// largeFormula returns a random 3-SAT formula with n clauses over n/2
+// variables, large enough that the cost of cgo calls dominates Add.
+func largeFormula(n int) Formula {
+	rnd := rand.New(rand.NewSource(1))
+	formula := make(Formula, n)
+	for i := range formula {
+		clause := make(Clause, 3)
+		for j := range clause {
+			clause[j] = Literal(rnd.Intn(n/2) + 1)
+			if rnd.Intn(2) == 0 {
+				clause[j] = -clause[j]
+			}
+		}
+		formula[i] = clause
+	}
+	return formula
+}
+
+// BenchmarkAddLarge measures how long it takes to add a formula with many
+// clauses in one call to Add.
+func BenchmarkAddLarge(b *testing.B) {
+	b.StopTimer()
+	formula := largeFormula(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, _ := New(nil)
+		b.StartTimer()
+		p.Add(formula)
+		b.StopTimer()
+		p.Delete()
+	}
+}
+
+// BenchmarkAddLargePerClause measures how long it takes to add the same
+// formula as BenchmarkAddLarge one clause per call to Add, which costs a cgo
+// call per clause.
+func BenchmarkAddLargePerClause(b *testing.B) {
+	b.StopTimer()
+	formula := largeFormula(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, _ := New(nil)
+		b.StartTimer()
+		for _, clause := range formula {
+			p.Add(Formula{clause})
+		}
+		b.StopTimer()
+		p.Delete()
+	}
+}
+
 // BenchmarkBlockSolution measures how long it takes to add a clause negating
 // the last solution.
 func BenchmarkBlockSolution(b *testing.B) {
@@ -0,0 +1,107 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+// This file contains the function C calls back into, which cgo requires to be
+// in a file whose preamble has only declarations.
+
+// #include <stdint.h>
+import "C"
+import (
+	"sync"
+	"time"
+)
+
+// Progress reports how far PicoSAT has gotten in a call to Solve or a similar
+// method. See Options.Progress. The counters are totals over the Pigosat
+// object's lifetime.
+type Progress struct {
+	Propagations uint64        // Literals assigned by unit propagation
+	Decisions    uint64        // Literals assigned by decision
+	Visits       uint64        // Clauses visited during propagation
+	Elapsed      time.Duration // Time since the current call began
+}
+
+// progressState is what C's interrupt hook needs to call Options.Progress.
+type progressState struct {
+	callback func(Progress) bool
+	start    time.Time   // When the current call to picosat_sat began
+	panicked interface{} // What the callback panicked with, if it did
+}
+
+// C cannot hold Go pointers, so C refers to each Pigosat object's
+// progressState by a key into progressStates.
+var (
+	progressLock   sync.Mutex
+	progressStates = make(map[uintptr]*progressState)
+	progressNext   uintptr
+)
+
+// registerProgress stores a progressState for callback and returns it and its
+// nonzero key.
+func registerProgress(callback func(Progress) bool) (uintptr, *progressState) {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	progressNext++
+	state := &progressState{callback: callback}
+	progressStates[progressNext] = state
+	return progressNext, state
+}
+
+// lookupProgress returns the progressState stored under key.
+func lookupProgress(key uintptr) *progressState {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	return progressStates[key]
+}
+
+// unregisterProgress deletes the progressState stored under key.
+func unregisterProgress(key uintptr) {
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	delete(progressStates, key)
+}
+
+// begin prepares s for a call into PicoSAT that may call pigosatProgress. s
+// may be nil.
+func (s *progressState) begin() {
+	if s != nil {
+		s.start = time.Now()
+		s.panicked = nil
+	}
+}
+
+// end panics if the callback panicked since begin. s may be nil.
+func (s *progressState) end() {
+	if s != nil && s.panicked != nil {
+		r := s.panicked
+		s.panicked = nil
+		panic(r)
+	}
+}
+
+// pigosatProgress calls the Options.Progress callback stored under key and
+// returns 1 to stop picosat_sat or 0 to continue. A panic must not unwind
+// through C's stack, so pigosatProgress recovers it, stops picosat_sat, and
+// leaves it for progressState.end to panic with again.
+//
+//export pigosatProgress
+func pigosatProgress(key C.uintptr_t, propagations, decisions,
+	visits C.ulonglong) (stop C.int) {
+	state := lookupProgress(uintptr(key))
+	defer func() {
+		if r := recover(); r != nil {
+			state.panicked = r
+			stop = 1
+		}
+	}()
+	if state.callback(Progress{
+		Propagations: uint64(propagations),
+		Decisions:    uint64(decisions),
+		Visits:       uint64(visits),
+		Elapsed:      time.Since(state.start),
+	}) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import "testing"
+
+// TestProgress tests that Solve calls Options.Progress with growing counters.
+func TestProgress(t *testing.T) {
+	var reports []Progress
+	p, err := New(&Options{Progress: func(progress Progress) bool {
+		reports = append(reports, progress)
+		return false
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Delete()
+	p.Add(pigeonhole(8))
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected Unsatisfiable, got %v", status)
+	}
+	if len(reports) == 0 {
+		t.Fatal("Progress was never called")
+	}
+	for i, progress := range reports {
+		if progress.Decisions == 0 || progress.Propagations == 0 ||
+			progress.Visits == 0 || progress.Elapsed <= 0 {
+			t.Errorf("reports[%d] = %+v has a zero counter", i, progress)
+		}
+		if i > 0 && (progress.Decisions <= reports[i-1].Decisions ||
+			progress.Elapsed < reports[i-1].Elapsed) {
+			t.Errorf("reports[%d] = %+v did not grow from %+v", i, progress,
+				reports[i-1])
+		}
+	}
+}
+
+// TestProgressStop tests that returning true from Options.Progress stops
+// Solve, and that panicking in it stops Solve and panics again from Solve.
+func TestProgressStop(t *testing.T) {
+	action := "stop"
+	calls := 0
+	p, _ := New(&Options{Progress: func(Progress) bool {
+		calls++
+		switch action {
+		case "stop":
+			return true
+		case "panic":
+			panic("progress panicked")
+		}
+		return false
+	}})
+	defer p.Delete()
+	p.Add(pigeonhole(8))
+	if solution, status := p.Solve(); status != Unknown || solution != nil {
+		t.Errorf("Expected Unknown, got %v, %v", status, solution)
+	}
+	if calls != 1 {
+		t.Errorf("Expected one call to Progress, got %d", calls)
+	}
+
+	action, calls = "panic", 0
+	assertPanics(t, "Solve", func() { p.Solve() })
+	if calls != 1 {
+		t.Errorf("Expected one call to Progress, got %d", calls)
+	}
+
+	// p is still usable after the panic.
+	action = "continue"
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Errorf("Expected Unsatisfiable, got %v", status)
+	}
+}
+
+// TestProgressMemoryLimit tests that Options.MemoryLimit still stops Solve
+// when Options.Progress is set, and that Delete forgets the callback.
+func TestProgressMemoryLimit(t *testing.T) {
+	formula := pigeonhole(11)
+	calls := 0
+	p, _ := New(&Options{MemoryLimit: 1, Progress: func(Progress) bool {
+		calls++
+		return false
+	}})
+	p.Add(formula)
+	if _, status := p.Solve(); status != Unknown {
+		t.Errorf("Expected Unknown, got %v", status)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no calls to Progress, got %d", calls)
+	}
+	progressLock.Lock()
+	before := len(progressStates)
+	progressLock.Unlock()
+	p.Delete()
+	progressLock.Lock()
+	after := len(progressStates)
+	progressLock.Unlock()
+	if after != before-1 {
+		t.Errorf("Delete left %d progress states, expected %d", after, before-1)
+	}
+}
+
+// TestProgressAssumptions tests that Options.Progress cannot stop the searches
+// inside MinUnsatisfiableAssumptions and the maximal satisfiable subset
+// methods, which PicoSAT aborts the process for.
+func TestProgressAssumptions(t *testing.T) {
+	calls := 0
+	p, _ := New(&Options{Progress: func(Progress) bool {
+		calls++
+		return true
+	}})
+	defer p.Delete()
+	p.Add(Formula{{-1, -2}})
+	for v := Literal(3); v < 6003; v += 2 {
+		p.Add(Formula{{v, v + 1}})
+	}
+	p.Assume(1)
+	p.Assume(2)
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected Unsatisfiable, got %v", status)
+	}
+	if mus := p.MinUnsatisfiableAssumptions(); len(mus) != 2 {
+		t.Errorf("Expected two assumptions, got %v", mus)
+	}
+	p.Assume(1)
+	p.Assume(2)
+	if mss := p.MaxSatisfiableAssumptions(); len(mss) != 1 {
+		t.Errorf("Expected one assumption, got %v", mss)
+	}
+	// MaxSatisfiableAssumptions reassumes the assumptions.
+	if mss := p.NextMaxSatisfiableAssumptions(); len(mss) != 1 {
+		t.Errorf("Expected one assumption, got %v", mss)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no calls to Progress, got %d", calls)
+	}
+}
@@ -0,0 +1,311 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Quantifier is the quantifier of a block of variables in a quantified Boolean
+// formula.
+type Quantifier int
+
+const (
+	// Exists quantifies variables existentially.
+	Exists Quantifier = iota
+	// Forall quantifies variables universally.
+	Forall
+)
+
+// String returns "e" for Exists and "a" for Forall, as in QDIMACS.
+func (q Quantifier) String() string {
+	switch q {
+	case Exists:
+		return "e"
+	case Forall:
+		return "a"
+	}
+	return fmt.Sprintf("Quantifier(%d)", int(q))
+}
+
+// QuantifierBlock is a set of variables sharing a quantifier in the prefix of
+// a quantified Boolean formula.
+type QuantifierBlock struct {
+	Quantifier Quantifier
+	Variables  []Literal
+}
+
+// ReadQDIMACS reads a quantified Boolean formula in QDIMACS format from r. The
+// format is DIMACS CNF, as ReadDIMACS reads, with lines "e <variable> ... 0"
+// and "a <variable> ... 0" between the header and the clauses quantifying
+// variables existentially and universally, outermost first. ReadQDIMACS
+// returns the quantifier prefix and the matrix: the clauses. Adjacent blocks
+// with the same quantifier are merged, and variables in the matrix that no
+// block quantifies are added to an outermost existential block, as QDIMACS
+// prescribes. ReadQDIMACS returns an error if the input is malformed or a
+// variable is quantified more than once. Like ReadDIMACS, ReadQDIMACS
+// decompresses compressed input. See SolveQBF.
+func ReadQDIMACS(r io.Reader) (prefix []QuantifierBlock, matrix Formula,
+	variables int, err error) {
+	if r, _, err = Decompress(r); err != nil {
+		return nil, nil, 0, err
+	}
+	// Separate the quantifier lines, replacing them with comments so that
+	// ReadDIMACS's line numbers stay right.
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	var rest bytes.Buffer
+	var line int
+	header, clauses := false, false
+	var lines []int // Line number of each element of prefix
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		fields := strings.Fields(text)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "c") || fields[0] == "%" {
+			rest.WriteString(text)
+		} else if fields[0] == "p" {
+			header = true
+			rest.WriteString(text)
+		} else if fields[0] != "a" && fields[0] != "e" {
+			clauses = true
+			rest.WriteString(text)
+		} else {
+			if !header || clauses {
+				return nil, nil, 0, fmt.Errorf(
+					"line %d: quantifiers must come between the header and the clauses",
+					line)
+			}
+			block := QuantifierBlock{Quantifier: Exists}
+			if fields[0] == "a" {
+				block.Quantifier = Forall
+			}
+			if fields[len(fields)-1] != "0" {
+				return nil, nil, 0, fmt.Errorf("line %d: missing final 0", line)
+			}
+			for _, field := range fields[1 : len(fields)-1] {
+				v, err := strconv.ParseInt(field, 10, 32)
+				if err != nil || v <= 0 {
+					return nil, nil, 0, fmt.Errorf("line %d: invalid variable %q",
+						line, field)
+				}
+				block.Variables = append(block.Variables, Literal(v))
+			}
+			if n := len(prefix); n > 0 && prefix[n-1].Quantifier == block.Quantifier {
+				prefix[n-1].Variables = append(prefix[n-1].Variables, block.Variables...)
+				lines[n-1] = line
+			} else {
+				prefix = append(prefix, block)
+				lines = append(lines, line)
+			}
+			rest.WriteString("c")
+		}
+		rest.WriteByte('\n')
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+	if matrix, variables, err = ReadDIMACS(&rest); err != nil {
+		return nil, nil, 0, err
+	}
+
+	quantified := make(map[Literal]bool)
+	for i, block := range prefix {
+		for _, v := range block.Variables {
+			if int(v) > variables {
+				return nil, nil, 0, fmt.Errorf(
+					"line %d: variable %d exceeds the %d variables in the header",
+					lines[i], v, variables)
+			}
+			if quantified[v] {
+				return nil, nil, 0, fmt.Errorf("line %d: variable %d quantified twice",
+					lines[i], v)
+			}
+			quantified[v] = true
+		}
+	}
+	var free []Literal
+	for _, clause := range matrix {
+		for _, lit := range clause {
+			if v := variableOf(lit); !quantified[v] {
+				quantified[v] = true
+				free = append(free, v)
+			}
+		}
+	}
+	if len(free) > 0 {
+		sort.Slice(free, func(i, j int) bool { return free[i] < free[j] })
+		if len(prefix) > 0 && prefix[0].Quantifier == Exists {
+			prefix[0].Variables = append(free, prefix[0].Variables...)
+		} else {
+			prefix = append([]QuantifierBlock{{Exists, free}}, prefix...)
+		}
+	}
+	return prefix, matrix, variables, nil
+}
+
+// SolveQBF decides the quantified Boolean formula with quantifier prefix prefix
+// and matrix matrix, such as ReadQDIMACS returns. It supports prefixes with at
+// most one alternation from Exists to Forall, as in "exists X forall Y", and
+// returns an error for other prefixes. Variables in matrix that prefix does not
+// quantify are existential. See SolveExistsForall for the meaning of the
+// return values.
+func SolveQBF(prefix []QuantifierBlock, matrix Formula, options *Options) (witness []Literal,
+	status Status, err error) {
+	var exists, forall []Literal
+	for _, block := range prefix {
+		if len(block.Variables) == 0 {
+			continue
+		}
+		switch {
+		case block.Quantifier == Exists && forall == nil:
+			exists = append(exists, block.Variables...)
+		case block.Quantifier == Forall:
+			forall = append(forall, block.Variables...)
+		default:
+			return nil, Unknown, fmt.Errorf(
+				"only prefixes of the form \"exists X forall Y\" are supported")
+		}
+	}
+	return SolveExistsForall(exists, forall, matrix, options)
+}
+
+// SolveExistsForall decides the quantified Boolean formula "there exist values
+// of the variables in exists such that, for all values of the variables in
+// forall, matrix is true." Variables in matrix that are in neither list are
+// existential, and the signs of the literals in the lists are ignored. If the
+// formula is true, status is Satisfiable and witness lists literals over the
+// existential variables, in order of increasing variable, such that every
+// assignment agreeing with witness makes matrix true regardless of the
+// universal variables. Existential variables missing from witness may take
+// either value. If the formula is false, status is Unsatisfiable. If a limit
+// in options stops the search, status is Unknown. SolveExistsForall returns an
+// error if New does or if a variable is in both lists.
+//
+// SolveExistsForall is a counterexample-guided abstraction refinement (CEGAR)
+// loop over two Pigosat instances. The first proposes values for the
+// existential variables satisfying the matrix for every universal assignment
+// found so far. The second, under the assumption of the proposal, looks for an
+// assignment of the universal variables falsifying the matrix. If there is
+// one, the first instance learns the matrix with that assignment substituted
+// in. Otherwise, the proposal's failed assumptions are the witness. The loop
+// runs at most once per universal assignment, and usually far fewer times.
+func SolveExistsForall(exists, forall []Literal, matrix Formula, options *Options) (witness []Literal,
+	status Status, err error) {
+	// Selectors must not collide with any variable, even one the matrix lacks.
+	maxVar := Literal(0)
+	isForall := make(map[Literal]bool, len(forall))
+	for _, lit := range forall {
+		v := variableOf(lit)
+		isForall[v] = true
+		if v > maxVar {
+			maxVar = v
+		}
+	}
+	existential := make(map[Literal]bool)
+	for _, lit := range exists {
+		v := variableOf(lit)
+		if isForall[v] {
+			return nil, Unknown, fmt.Errorf("variable %d is both existential and universal", v)
+		}
+		if v != 0 {
+			existential[v] = true
+		}
+		if v > maxVar {
+			maxVar = v
+		}
+	}
+	clauses := make([][]Literal, len(matrix))
+	for i, clause := range matrix {
+		for _, lit := range clause {
+			if lit == 0 {
+				break
+			}
+			v := variableOf(lit)
+			if v > maxVar {
+				maxVar = v
+			}
+			if !isForall[v] {
+				existential[v] = true
+			}
+			clauses[i] = append(clauses[i], lit)
+		}
+	}
+
+	candidates, err := New(options)
+	if err != nil {
+		return nil, Unknown, err
+	}
+	defer candidates.Delete()
+	counterexamples, err := New(options)
+	if err != nil {
+		return nil, Unknown, err
+	}
+	defer counterexamples.Delete()
+
+	// Selector maxVar+1+i implies that clause i is false. Some clause must be.
+	some := make(Clause, len(clauses))
+	var negation Formula
+	for i, clause := range clauses {
+		selector := maxVar + 1 + Literal(i)
+		some[i] = selector
+		for _, lit := range clause {
+			negation = append(negation, Clause{-selector, -lit})
+		}
+	}
+	counterexamples.Add(append(negation, some))
+
+	existVars := make([]Literal, 0, len(existential))
+	for v := range existential {
+		existVars = append(existVars, v)
+	}
+	sort.Slice(existVars, func(i, j int) bool { return existVars[i] < existVars[j] })
+
+	for {
+		proposal, status := candidates.Solve()
+		if status != Satisfiable {
+			return nil, status, nil
+		}
+		for _, v := range existVars {
+			// Variables the first instance has not seen yet are false.
+			if int(v) < len(proposal) && proposal[v] {
+				counterexamples.Assume(v)
+			} else {
+				counterexamples.Assume(-v)
+			}
+		}
+		counter, status := counterexamples.Solve()
+		switch status {
+		case Unknown:
+			return nil, Unknown, nil
+		case Unsatisfiable:
+			witness = counterexamples.FailedAssumptions()
+			sort.Slice(witness, func(i, j int) bool {
+				return variableOf(witness[i]) < variableOf(witness[j])
+			})
+			return witness, Satisfiable, nil
+		}
+		// Substitute the counterexample's universal values into the matrix.
+		var refinement Formula
+	clauseLoop:
+		for _, clause := range clauses {
+			var reduced Clause
+			for _, lit := range clause {
+				v := variableOf(lit)
+				if !isForall[v] {
+					reduced = append(reduced, lit)
+				} else if counter[v] == (lit > 0) {
+					continue clauseLoop
+				}
+			}
+			refinement = append(refinement, reduced)
+		}
+		candidates.Add(refinement)
+	}
+}
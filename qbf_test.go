@@ -0,0 +1,173 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadQDIMACS(t *testing.T) {
+	good := []struct {
+		qdimacs   string
+		prefix    []QuantifierBlock
+		matrix    Formula
+		variables int
+	}{
+		{"p cnf 0 0\n", nil, Formula{}, 0},
+		{"c comment\np cnf 4 2\ne 1 0\na 2 3 0\na 4 0\n1 2 0\n-3 4 0\n",
+			[]QuantifierBlock{{Exists, []Literal{1}}, {Forall, []Literal{2, 3, 4}}},
+			Formula{{1, 2}, {-3, 4}}, 4},
+		{"p cnf 5 2\na 2 0\ne 3 0\n5 2 0\n-3 1 0\n",
+			[]QuantifierBlock{{Exists, []Literal{1, 5}}, {Forall, []Literal{2}},
+				{Exists, []Literal{3}}},
+			Formula{{5, 2}, {-3, 1}}, 5},
+		{"p cnf 3 1\ne 2 0\n3 -1 2 0\n",
+			[]QuantifierBlock{{Exists, []Literal{1, 3, 2}}}, Formula{{3, -1, 2}}, 3},
+	}
+	for i, qt := range good {
+		prefix, matrix, variables, err := ReadQDIMACS(strings.NewReader(qt.qdimacs))
+		if err != nil {
+			t.Errorf("good[%d]: %v", i, err)
+		}
+		if !reflect.DeepEqual(prefix, qt.prefix) || !reflect.DeepEqual(matrix, qt.matrix) ||
+			variables != qt.variables {
+			t.Errorf("good[%d]: expected %v, %v, %d; got %v, %v, %d", i, qt.prefix,
+				qt.matrix, qt.variables, prefix, matrix, variables)
+		}
+	}
+
+	bad := []string{
+		"e 1 0\np cnf 1 1\n1 0\n",
+		"p cnf 2 1\n1 2 0\ne 1 0\n",
+		"p cnf 1 1\ne 1\n1 0\n",
+		"p cnf 1 1\ne x 0\n1 0\n",
+		"p cnf 1 1\ne -1 0\n1 0\n",
+		"p cnf 1 1\ne 2 0\n1 0\n",
+		"p cnf 1 1\ne 1 0\na 1 0\n1 0\n",
+		"p cnf 1 1\ne 1 0\n2 0\n",
+	}
+	for i, qdimacs := range bad {
+		if _, _, _, err := ReadQDIMACS(strings.NewReader(qdimacs)); err == nil {
+			t.Errorf("bad[%d]: expected an error reading %q", i, qdimacs)
+		}
+	}
+}
+
+// bruteExistsForall decides exists x_1..x_e forall x_{e+1}..x_{e+a} matrix by
+// brute force.
+func bruteExistsForall(e, a int, matrix Formula) bool {
+	for x := 0; x < 1<<uint(e); x++ {
+		all := true
+		for y := 0; all && y < 1<<uint(a); y++ {
+			all = evaluate(matrix, assignment(x|y<<uint(e), e+a))
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// assignment returns the Solution whose variable v is bit v-1 of bits.
+func assignment(bits, n int) Solution {
+	s := make(Solution, n+1)
+	for v := 1; v <= n; v++ {
+		s[v] = bits&(1<<uint(v-1)) != 0
+	}
+	return s
+}
+
+func TestSolveExistsForall(t *testing.T) {
+	const e, a = 3, 3
+	exists, forall := []Literal{1, 2, 3}, []Literal{4, 5, 6}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		var matrix Formula
+		for j := rnd.Intn(8); j >= 0; j-- {
+			var clause Clause
+			for k := rnd.Intn(4); k >= 0; k-- {
+				lit := Literal(rnd.Intn(e+a) + 1)
+				if rnd.Intn(2) == 0 {
+					lit = -lit
+				}
+				clause = append(clause, lit)
+			}
+			matrix = append(matrix, clause)
+		}
+		witness, status, err := SolveExistsForall(exists, forall, matrix, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := bruteExistsForall(e, a, matrix); expected != (status == Satisfiable) {
+			t.Errorf("%v: expected %t, got %v", matrix, expected, status)
+			continue
+		}
+		if status != Satisfiable {
+			continue
+		}
+		for _, lit := range witness {
+			if variableOf(lit) > e {
+				t.Errorf("%v: witness %v has universal variables", matrix, witness)
+			}
+		}
+		// Every assignment agreeing with witness must satisfy matrix.
+		for bits := 0; bits < 1<<uint(e+a); bits++ {
+			s := assignment(bits, e+a)
+			agrees := true
+			for _, lit := range witness {
+				agrees = agrees && s[variableOf(lit)] == (lit > 0)
+			}
+			if agrees && !evaluate(matrix, s) {
+				t.Errorf("%v: witness %v fails for %v", matrix, witness, s)
+				break
+			}
+		}
+	}
+
+	if _, _, err := SolveExistsForall([]Literal{1}, []Literal{-1}, nil, nil); err == nil {
+		t.Error("Expected an error for a variable in both lists")
+	}
+	witness, status, err := SolveExistsForall(nil, []Literal{2}, Formula{{1, 2}, {1, -2}}, nil)
+	if !reflect.DeepEqual(witness, []Literal{1}) || status != Satisfiable || err != nil {
+		t.Errorf("Expected [1], Satisfiable, nil; got %v, %v, %v", witness, status, err)
+	}
+	// Existential variables missing from the matrix must not collide with the
+	// selectors numbered after the matrix's variables.
+	_, status, err = SolveExistsForall([]Literal{2}, []Literal{1}, Formula{{1}}, nil)
+	if status != Unsatisfiable || err != nil {
+		t.Errorf("Expected Unsatisfiable, nil; got %v, %v", status, err)
+	}
+}
+
+func TestSolveQBF(t *testing.T) {
+	tests := []struct {
+		qdimacs string
+		status  Status
+		err     bool
+	}{
+		{"p cnf 2 2\ne 1 0\na 2 0\n1 2 0\n1 -2 0\n", Satisfiable, false},
+		{"p cnf 2 2\ne 1 0\na 2 0\n1 2 0\n-1 -2 0\n", Unsatisfiable, false},
+		{"p cnf 2 2\na 2 0\n1 2 0\n1 -2 0\n", Satisfiable, false},
+		{"p cnf 1 1\na 1 0\n1 0\n", Unsatisfiable, false},
+		{"p cnf 1 0\na 1 0\n", Satisfiable, false},
+		{"p cnf 2 1\n1 -2 0\n", Satisfiable, false},
+		{"p cnf 2 1\ne 2 0\n0\n", Unsatisfiable, false},
+		{"p cnf 3 1\na 1 0\ne 2 0\na 3 0\n1 2 3 0\n", Unknown, true},
+		{"p cnf 2 1\na 1 0\ne 2 0\n1 2 0\n", Unknown, true},
+		{"p cnf 2 1\ne 2 0\na 1 0\n1 0\n", Unsatisfiable, false},
+	}
+	for i, qt := range tests {
+		prefix, matrix, _, err := ReadQDIMACS(strings.NewReader(qt.qdimacs))
+		if err != nil {
+			t.Fatalf("tests[%d]: %v", i, err)
+		}
+		_, status, err := SolveQBF(prefix, matrix, nil)
+		if status != qt.status || (err != nil) != qt.err {
+			t.Errorf("tests[%d]: expected %v, error %t; got %v, %v", i, qt.status,
+				qt.err, status, err)
+		}
+	}
+}
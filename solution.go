@@ -0,0 +1,91 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+// Literals returns s as a list of literals, one per variable in increasing
+// order, positive if the variable is true and negative if it is false. This is
+// the form in which DIMACS solvers print solutions and the form Assume takes.
+func (s Solution) Literals() []Literal {
+	if len(s) <= 1 {
+		return nil
+	}
+	lits := make([]Literal, len(s)-1)
+	for v, value := range s[1:] {
+		if value {
+			lits[v] = Literal(v + 1)
+		} else {
+			lits[v] = Literal(-v - 1)
+		}
+	}
+	return lits
+}
+
+// SolutionFromLiterals returns the Solution setting the variable of each of
+// lits true if the literal is positive and false if it is negative. The
+// solution's length is one more than the largest variable in lits. Variables
+// missing from lits are false, and zeros in lits are ignored. If lits sets a
+// variable both true and false, the last literal wins.
+func SolutionFromLiterals(lits []Literal) Solution {
+	variables := Literal(0)
+	for _, lit := range lits {
+		if v := variableOf(lit); v > variables {
+			variables = v
+		}
+	}
+	s := make(Solution, variables+1)
+	for _, lit := range lits {
+		if lit != 0 {
+			s[variableOf(lit)] = lit > 0
+		}
+	}
+	return s
+}
+
+// Restrict returns the literals of s over the variables in vars, in the order
+// of vars, positive if the variable is true and negative if it is false. This
+// is s projected onto vars, as Enumerate reports solutions. The signs of the
+// literals in vars are ignored, and variables beyond s's length are false.
+func (s Solution) Restrict(vars []Literal) []Literal {
+	lits := make([]Literal, 0, len(vars))
+	for _, lit := range vars {
+		v := variableOf(lit)
+		if v == 0 {
+			continue
+		}
+		if int(v) < len(s) && s[v] {
+			lits = append(lits, v)
+		} else {
+			lits = append(lits, -v)
+		}
+	}
+	return lits
+}
+
+// Diff returns the variables, in increasing order, whose values differ between
+// s and other. Variables beyond either solution's length are false in it.
+func (s Solution) Diff(other Solution) []Literal {
+	n := len(s)
+	if len(other) > n {
+		n = len(other)
+	}
+	var vars []Literal
+	for v := 1; v < n; v++ {
+		if (v < len(s) && s[v]) != (v < len(other) && other[v]) {
+			vars = append(vars, Literal(v))
+		}
+	}
+	return vars
+}
+
+// Clause returns the clause that rules out s, which is the clause that
+// BlockSolution adds: the negation of every literal of s.Literals(). Adding
+// the clause to a Pigosat instance with len(s)-1 variables is the same as
+// calling BlockSolution. To rule out only s's values over some variables, as
+// Enumerate does, negate the literals Restrict returns.
+func (s Solution) Clause() Clause {
+	lits := s.Literals()
+	for i := range lits {
+		lits[i] = -lits[i]
+	}
+	return Clause(lits)
+}
@@ -0,0 +1,82 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSolutionLiterals(t *testing.T) {
+	s := Solution{false, true, false, false, true}
+	lits := []Literal{1, -2, -3, 4}
+	if !reflect.DeepEqual(s.Literals(), lits) {
+		t.Errorf("Literals() = %v, expected %v", s.Literals(), lits)
+	}
+	if !reflect.DeepEqual(SolutionFromLiterals(lits), s) {
+		t.Errorf("SolutionFromLiterals(%v) = %v, expected %v", lits,
+			SolutionFromLiterals(lits), s)
+	}
+	if got := SolutionFromLiterals([]Literal{-3, 1, 0, 1, -1}); !reflect.DeepEqual(got,
+		Solution{false, false, false, false}) {
+		t.Errorf("Unexpected %v from literals with duplicates", got)
+	}
+	for _, empty := range []Solution{nil, {false}} {
+		if empty.Literals() != nil || empty.Clause() != nil {
+			t.Errorf("%v should have no literals", empty)
+		}
+	}
+	if got := SolutionFromLiterals(nil); !reflect.DeepEqual(got, Solution{false}) {
+		t.Errorf("SolutionFromLiterals(nil) = %v", got)
+	}
+	if clause := s.Clause(); !reflect.DeepEqual(clause, Clause{-1, 2, 3, -4}) {
+		t.Errorf("Clause() = %v", clause)
+	}
+}
+
+func TestSolutionRestrictDiff(t *testing.T) {
+	s := Solution{false, true, false, false, true}
+	if got := s.Restrict([]Literal{4, -1, 0, 2, 6}); !reflect.DeepEqual(got,
+		[]Literal{4, 1, -2, -6}) {
+		t.Errorf("Restrict = %v", got)
+	}
+	if got := s.Restrict(nil); len(got) != 0 {
+		t.Errorf("Restrict(nil) = %v", got)
+	}
+	other := Solution{false, true, true, false, true, false, true}
+	if got := s.Diff(other); !reflect.DeepEqual(got, []Literal{2, 6}) {
+		t.Errorf("Diff = %v", got)
+	}
+	if got := other.Diff(s); !reflect.DeepEqual(got, []Literal{2, 6}) {
+		t.Errorf("Diff = %v", got)
+	}
+	if got := s.Diff(s); got != nil {
+		t.Errorf("Diff with itself = %v", got)
+	}
+}
+
+// TestSolutionClauseBlocks tests that adding Solution.Clause blocks the
+// solution as BlockSolution does.
+func TestSolutionClauseBlocks(t *testing.T) {
+	for _, ft := range formulaTests {
+		p, _ := New(nil)
+		q, _ := New(nil)
+		p.Add(ft.formula)
+		q.Add(ft.formula)
+		for {
+			want, wantStatus := p.Solve()
+			got, status := q.Solve()
+			if status != wantStatus || !reflect.DeepEqual(got, want) {
+				t.Fatalf("Solutions diverged: %v, %v != %v, %v", got, status,
+					want, wantStatus)
+			}
+			if status != Satisfiable {
+				break
+			}
+			p.BlockSolution(want)
+			q.Add(Formula{got.Clause()})
+		}
+		p.Delete()
+		q.Delete()
+	}
+}
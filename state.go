@@ -0,0 +1,58 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import "fmt"
+
+// solverState mirrors the state PicoSAT keeps internally. Many PicoSAT
+// functions abort the whole process unless PicoSAT is in a particular state,
+// so Pigosat tracks the state to check those functions' preconditions and
+// return errors instead.
+type solverState int
+
+const (
+	// stateReady is PicoSAT's READY state, which it enters on creation and
+	// whenever clauses or assumptions are added. Only adding clauses and
+	// assumptions and solving are allowed, which are allowed in every state.
+	stateReady solverState = iota
+	// stateSat is PicoSAT's SAT state, which it enters when picosat_sat
+	// returns PICOSAT_SATISFIABLE. It permits picosat_deref.
+	stateSat
+	// stateUnsat is PicoSAT's UNSAT state, which it enters when picosat_sat
+	// returns PICOSAT_UNSATISFIABLE. It permits the functions about failed
+	// assumptions and cores.
+	stateUnsat
+	// stateUnknown is PicoSAT's UNKNOWN state, which it enters when
+	// picosat_sat reaches a limit and returns PICOSAT_UNKNOWN.
+	stateUnknown
+)
+
+// String returns PicoSAT's name for s.
+func (s solverState) String() string {
+	switch s {
+	case stateReady:
+		return "READY"
+	case stateSat:
+		return "SAT"
+	case stateUnsat:
+		return "UNSAT"
+	case stateUnknown:
+		return "UNKNOWN"
+	}
+	return "invalid state"
+}
+
+// setState records the state PicoSAT enters when picosat_sat returns status.
+// This private method does not acquire the lock or check if p is nil.
+func (p *Pigosat) setState(status Status) {
+	switch status {
+	case Satisfiable:
+		p.state = stateSat
+	case Unsatisfiable:
+		p.state = stateUnsat
+	case Unknown:
+		p.state = stateUnknown
+	default:
+		panic(fmt.Errorf("Unknown sat status: %d", status))
+	}
+}
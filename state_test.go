@@ -0,0 +1,162 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TestStateValue tests that Value returns ErrNotSatisfiable in every state but
+// SAT, where PicoSAT would abort.
+func TestStateValue(t *testing.T) {
+	p, _ := New(nil)
+	defer p.Delete()
+	if _, err := p.Value(1); !errors.Is(err, ErrNotSatisfiable) {
+		t.Errorf("Before Solve: expected ErrNotSatisfiable, got %v", err)
+	}
+	p.Add(Formula{{1}, {-2, 3}})
+	if _, err := p.Value(1); !errors.Is(err, ErrNotSatisfiable) {
+		t.Errorf("After Add: expected ErrNotSatisfiable, got %v", err)
+	}
+	solution, _ := p.Solve()
+	for v := Literal(1); v <= 3; v++ {
+		if value, err := p.Value(v); err != nil || value != solution[v] {
+			t.Errorf("Value(%d) = %v, %v; expected %v", v, value, err, solution[v])
+		}
+		if value, err := p.Value(-v); err != nil || value == solution[v] {
+			t.Errorf("Value(%d) = %v, %v; expected %v", -v, value, err, !solution[v])
+		}
+	}
+	var litErr *LiteralError
+	for _, lit := range []Literal{0, 4, math.MinInt32} {
+		if _, err := p.Value(lit); !errors.As(err, &litErr) {
+			t.Errorf("Value(%d): expected a *LiteralError, got %v", lit, err)
+		}
+	}
+	p.Assume(-1)
+	if _, err := p.Value(1); !errors.Is(err, ErrNotSatisfiable) {
+		t.Errorf("After Assume: expected ErrNotSatisfiable, got %v", err)
+	}
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected Unsatisfiable, got %v", status)
+	}
+	if _, err := p.Value(1); !errors.Is(err, ErrNotSatisfiable) {
+		t.Errorf("After Unsatisfiable: expected ErrNotSatisfiable, got %v", err)
+	}
+
+	q, _ := New(&Options{PropagationLimit: 1})
+	defer q.Delete()
+	q.Add(formulaTests[benchTest].formula)
+	if _, status := q.Solve(); status != Unknown {
+		t.Fatalf("Expected Unknown, got %v", status)
+	}
+	if _, err := q.Value(1); !errors.Is(err, ErrNotSatisfiable) {
+		t.Errorf("After Unknown: expected ErrNotSatisfiable, got %v", err)
+	}
+}
+
+// TestStateAssumptions tests that the Checked assumption methods return
+// ErrNotUnsatisfiable once the assumptions are no longer valid.
+func TestStateAssumptions(t *testing.T) {
+	p, _ := New(nil)
+	defer p.Delete()
+	p.Add(Formula{{1, 2}, {1, 3}})
+	p.Assume(-1)
+	p.Assume(-2)
+	p.Assume(-3)
+	if _, err := p.FailedAssumptionsChecked(); !errors.Is(err, ErrNotUnsatisfiable) {
+		t.Errorf("Before Solve: expected ErrNotUnsatisfiable, got %v", err)
+	}
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected Unsatisfiable, got %v", status)
+	}
+	if p.FailedAssumption(math.MinInt32) {
+		t.Error("FailedAssumption(math.MinInt32) should be false")
+	}
+	mus, err := p.MinUnsatisfiableAssumptionsChecked()
+	if err != nil || len(mus) != 2 && len(mus) != 3 {
+		t.Errorf("Unexpected MUS %v, %v", mus, err)
+	}
+	// picosat_mus_assumptions leaves PicoSAT in the UNSAT state.
+	if failed, err := p.FailedAssumptionsChecked(); err != nil || len(failed) == 0 {
+		t.Errorf("After MinUnsatisfiableAssumptions: got %v, %v", failed, err)
+	}
+	p.Add(Formula{{4}})
+	if _, err := p.FailedAssumptionsChecked(); !errors.Is(err, ErrNotUnsatisfiable) {
+		t.Errorf("After Add: expected ErrNotUnsatisfiable, got %v", err)
+	}
+	if _, err := p.MinUnsatisfiableAssumptionsChecked(); !errors.Is(err, ErrNotUnsatisfiable) {
+		t.Errorf("After Add: expected ErrNotUnsatisfiable, got %v", err)
+	}
+	if failed := p.FailedAssumptions(); len(failed) != 0 {
+		t.Errorf("After Add: expected no failed assumptions, got %v", failed)
+	}
+}
+
+// TestStateCore tests the core queries and that they and the Write* methods
+// return errors instead of aborting after the formula changes.
+func TestStateCore(t *testing.T) {
+	p, _ := New(&Options{EnableTrace: true})
+	defer p.Delete()
+	// Clauses 0 and 1 conflict, clause 2 is irrelevant.
+	p.Add(Formula{{1}, {-1}, {2, 3}})
+	if _, err := p.CoreLiteral(1); !errors.Is(err, ErrNotUnsatisfiable) {
+		t.Errorf("Before Solve: expected ErrNotUnsatisfiable, got %v", err)
+	}
+	if _, status := p.Solve(); status != Unsatisfiable {
+		t.Fatalf("Expected Unsatisfiable, got %v", status)
+	}
+	var core []bool
+	for i := 0; i < 3; i++ {
+		in, err := p.CoreClause(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		core = append(core, in)
+	}
+	if !reflect.DeepEqual(core, []bool{true, true, false}) {
+		t.Errorf("Unexpected clausal core %v", core)
+	}
+	for _, i := range []int{-1, 3} {
+		if _, err := p.CoreClause(i); err == nil {
+			t.Errorf("CoreClause(%d): expected an error", i)
+		}
+	}
+	if in, err := p.CoreLiteral(1); err != nil || !in {
+		t.Errorf("CoreLiteral(1) = %v, %v", in, err)
+	}
+	if in, err := p.CoreLiteral(-2); err != nil || in {
+		t.Errorf("CoreLiteral(-2) = %v, %v", in, err)
+	}
+	if in, err := p.CoreLiteral(100); err != nil || in {
+		t.Errorf("CoreLiteral(100) = %v, %v", in, err)
+	}
+	var litErr *LiteralError
+	if _, err := p.CoreLiteral(0); !errors.As(err, &litErr) {
+		t.Errorf("CoreLiteral(0): expected a *LiteralError, got %v", err)
+	}
+
+	// Res stays Unsatisfiable after Add, but PicoSAT leaves the UNSAT state.
+	p.Add(Formula{{4}})
+	if p.Res() != Unsatisfiable {
+		t.Errorf("Expected Res to stay Unsatisfiable, got %v", p.Res())
+	}
+	if err := p.WriteClausalCore(new(bytes.Buffer)); !errors.Is(err, ErrNotUnsatisfiable) {
+		t.Errorf("WriteClausalCore after Add: expected ErrNotUnsatisfiable, got %v", err)
+	}
+	if _, err := p.CoreClause(0); !errors.Is(err, ErrNotUnsatisfiable) {
+		t.Errorf("CoreClause after Add: expected ErrNotUnsatisfiable, got %v", err)
+	}
+
+	q, _ := New(nil)
+	defer q.Delete()
+	q.Add(Formula{{1}, {-1}})
+	q.Solve()
+	if _, err := q.CoreLiteral(1); !errors.Is(err, ErrTraceDisabled) {
+		t.Errorf("Without EnableTrace: expected ErrTraceDisabled, got %v", err)
+	}
+}
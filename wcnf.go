@@ -0,0 +1,189 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// SoftClause is a clause of a weighted MaxSAT problem that solutions need not
+// satisfy. Weight is the cost of a solution that falsifies Clause.
+type SoftClause struct {
+	Clause Clause
+	Weight uint64
+}
+
+// ReadWCNF reads a weighted MaxSAT problem in either of the WCNF formats of
+// the MaxSAT Evaluations and returns its hard clauses, which every solution
+// must satisfy, and its soft clauses. variables is the header's variable
+// count, or the largest variable in the input if there is no header.
+//
+// In the format used through 2021, the input starts with a header line
+// "p wcnf <variables> <clauses> <top>", optionally preceded by comment lines
+// starting with "c", and each clause starts with its weight. Clauses whose
+// weight is at least top are hard. If the header omits top, every clause is
+// soft. In the format used since 2022, there is no header, hard clauses start
+// with "h", and soft clauses start with their weight. In both formats, weights
+// must be positive, the zero ending the last clause may be omitted, and
+// ReadWCNF returns an error if the input is malformed. With a header, ReadWCNF
+// also returns an error if a literal's variable exceeds the header's variable
+// count or if the number of clauses differs from the header's clause count.
+// Like ReadDIMACS, ReadWCNF decompresses compressed input.
+func ReadWCNF(r io.Reader) (hard Formula, soft []SoftClause, variables int, err error) {
+	if r, _, err = Decompress(r); err != nil {
+		return nil, nil, 0, err
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<30)
+	var clauses, line int
+	var top uint64 // Zero if there is no header or the header omits top.
+	header, body := false, false
+	inClause, isHard := false, false
+	var weight uint64
+	var clause Clause
+	end := func() {
+		if isHard {
+			hard = append(hard, clause)
+		} else {
+			soft = append(soft, SoftClause{Clause: clause, Weight: weight})
+		}
+		inClause, clause = false, nil
+	}
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "c") {
+			continue
+		}
+		if fields[0] == "p" {
+			if header || body {
+				return nil, nil, 0, fmt.Errorf("line %d: unexpected header", line)
+			}
+			if variables, clauses, top, err = parseWCNFHeader(fields); err != nil {
+				return nil, nil, 0, fmt.Errorf("line %d: %v", line, err)
+			}
+			header = true
+			continue
+		}
+		body = true
+		for _, field := range fields {
+			if !inClause {
+				inClause = true
+				if field == "h" && !header {
+					isHard = true
+					continue
+				}
+				if weight, err = strconv.ParseUint(field, 10, 64); err != nil || weight == 0 {
+					return nil, nil, 0, fmt.Errorf("line %d: invalid weight %q", line, field)
+				}
+				isHard = top != 0 && weight >= top
+				continue
+			}
+			limit := variables
+			if !header {
+				limit = math.MaxInt32
+			}
+			lit, err := parseLiteral(field, limit)
+			if err != nil {
+				return nil, nil, 0, fmt.Errorf("line %d: %v", line, err)
+			}
+			if v := int(variableOf(lit)); !header && v > variables {
+				variables = v
+			}
+			if lit == 0 {
+				end()
+				continue
+			}
+			clause = append(clause, lit)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, nil, 0, err
+	}
+	if inClause {
+		end()
+	}
+	if n := len(hard) + len(soft); header && n != clauses {
+		return nil, nil, 0, fmt.Errorf("header promised %d clauses, but found %d",
+			clauses, n)
+	}
+	return hard, soft, variables, nil
+}
+
+// parseWCNFHeader parses the fields of a header line
+// "p wcnf <variables> <clauses> [<top>]".
+func parseWCNFHeader(fields []string) (variables, clauses int, top uint64, err error) {
+	if len(fields) == 5 {
+		top, err = strconv.ParseUint(fields[4], 10, 64)
+		if err != nil || top == 0 {
+			return 0, 0, 0, fmt.Errorf("invalid top weight %q", fields[4])
+		}
+		fields = fields[:4]
+	}
+	variables, clauses, err = parseHeader(fields, "wcnf")
+	return variables, clauses, top, err
+}
+
+// WriteWCNF writes the MaxSAT problem with hard clauses hard and soft clauses
+// soft to w in the WCNF format used by the MaxSAT Evaluations since 2022.
+// Clauses end at their first zero. See ReadWCNF.
+func WriteWCNF(w io.Writer, hard Formula, soft []SoftClause) error {
+	bw := bufio.NewWriter(w)
+	var buf []byte
+	for _, clause := range hard {
+		buf = appendClause(append(buf[:0], 'h', ' '), clause)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	for _, sc := range soft {
+		buf = strconv.AppendUint(buf[:0], sc.Weight, 10)
+		buf = appendClause(append(buf, ' '), sc.Clause)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteOldWCNF is like WriteWCNF, but writes the format with a "p wcnf" header
+// that the MaxSAT Evaluations used through 2021. The header's variable count is
+// the largest variable in hard and soft, and its top weight is one more than
+// the sum of the soft clauses' weights. WriteOldWCNF returns an error if that
+// sum overflows.
+func WriteOldWCNF(w io.Writer, hard Formula, soft []SoftClause) error {
+	variables := maxVariable(hard)
+	top := uint64(1)
+	for _, sc := range soft {
+		if v := maxVariable(Formula{sc.Clause}); v > variables {
+			variables = v
+		}
+		if top+sc.Weight < top {
+			return fmt.Errorf("sum of soft clause weights overflows")
+		}
+		top += sc.Weight
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "p wcnf %d %d %d\n", variables, len(hard)+len(soft), top)
+	var buf []byte
+	for _, clause := range hard {
+		buf = strconv.AppendUint(buf[:0], top, 10)
+		buf = appendClause(append(buf, ' '), clause)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	for _, sc := range soft {
+		buf = strconv.AppendUint(buf[:0], sc.Weight, 10)
+		buf = appendClause(append(buf, ' '), sc.Clause)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
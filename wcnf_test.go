@@ -0,0 +1,103 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadWCNF(t *testing.T) {
+	good := []struct {
+		wcnf      string
+		hard      Formula
+		soft      []SoftClause
+		variables int
+	}{
+		{"p wcnf 0 0 1\n", nil, nil, 0},
+		{"c old\np wcnf 3 3 10\n10 1 -3 0\n2 2\n3 0\n10 -1 0\n", Formula{{1, -3}, {-1}},
+			[]SoftClause{{Clause{2, 3}, 2}}, 3},
+		{"p wcnf 2 2\n1 1 0 5 -2", nil,
+			[]SoftClause{{Clause{1}, 1}, {Clause{-2}, 5}}, 2},
+		{"p wcnf 2 1 4\n7 0\n", Formula{nil}, nil, 2},
+		{"", nil, nil, 0},
+		{"c new\nh 1 -3 0\n2 2 3 0\nh -1 0\n18446744073709551615 4 0\nc end\n",
+			Formula{{1, -3}, {-1}},
+			[]SoftClause{{Clause{2, 3}, 2}, {Clause{4}, 18446744073709551615}}, 4},
+		{"h 0 1 0 h 2", Formula{nil, {2}}, []SoftClause{{nil, 1}}, 2},
+	}
+	for i, wt := range good {
+		hard, soft, variables, err := ReadWCNF(strings.NewReader(wt.wcnf))
+		if err != nil {
+			t.Errorf("good[%d]: %v", i, err)
+		}
+		if !reflect.DeepEqual(hard, wt.hard) || !reflect.DeepEqual(soft, wt.soft) ||
+			variables != wt.variables {
+			t.Errorf("good[%d]: expected %v, %v, %d; got %v, %v, %d", i, wt.hard,
+				wt.soft, wt.variables, hard, soft, variables)
+		}
+	}
+
+	bad := []string{
+		"p wcnf 1 1 2\np wcnf 1 1 2\n2 1 0\n",
+		"h 1 0\np wcnf 1 1 2\n",
+		"p cnf 1 1\n1 0\n",
+		"p wcnf 1\n1 1 0\n",
+		"p wcnf 1 1 0\n1 1 0\n",
+		"p wcnf 1 1 x\n1 1 0\n",
+		"p wcnf 1 1 2\nh 1 0\n",
+		"p wcnf 1 1 2\n0 1 0\n",
+		"p wcnf 1 1 2\n1 2 0\n",
+		"p wcnf 1 2 2\n1 1 0\n",
+		"p wcnf 1 1 2\n1 x 0\n",
+		"-1 1 0\n",
+		"x 1 0\n",
+		"h 99999999999 0\n",
+	}
+	for i, wcnf := range bad {
+		if _, _, _, err := ReadWCNF(strings.NewReader(wcnf)); err == nil {
+			t.Errorf("bad[%d]: expected an error reading %q", i, wcnf)
+		}
+	}
+}
+
+func TestWriteWCNF(t *testing.T) {
+	hard := Formula{{1, -3, 0, 2}, {}}
+	soft := []SoftClause{{Clause{2, 4}, 3}, {Clause{-1}, 1}}
+	writers := []struct {
+		name  string
+		write func(*bytes.Buffer, Formula, []SoftClause) error
+		out   string
+	}{
+		{"WriteWCNF", func(b *bytes.Buffer, h Formula, s []SoftClause) error {
+			return WriteWCNF(b, h, s)
+		}, "h 1 -3 0\nh 0\n3 2 4 0\n1 -1 0\n"},
+		{"WriteOldWCNF", func(b *bytes.Buffer, h Formula, s []SoftClause) error {
+			return WriteOldWCNF(b, h, s)
+		}, "p wcnf 4 4 5\n5 1 -3 0\n5 0\n3 2 4 0\n1 -1 0\n"},
+	}
+	for _, wt := range writers {
+		var buf bytes.Buffer
+		if err := wt.write(&buf, hard, soft); err != nil {
+			t.Fatalf("%s: %v", wt.name, err)
+		}
+		if buf.String() != wt.out {
+			t.Errorf("%s: expected %q, got %q", wt.name, wt.out, buf.String())
+		}
+		h, s, variables, err := ReadWCNF(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", wt.name, err)
+		}
+		if !reflect.DeepEqual(h, Formula{{1, -3}, nil}) || !reflect.DeepEqual(s, soft) ||
+			variables != 4 {
+			t.Errorf("%s: read back %v, %v, %d", wt.name, h, s, variables)
+		}
+	}
+
+	overflow := []SoftClause{{Clause{1}, 1 << 63}, {Clause{2}, 1 << 63}}
+	if err := WriteOldWCNF(&bytes.Buffer{}, nil, overflow); err == nil {
+		t.Error("Expected an error from overflowing weights")
+	}
+}
@@ -0,0 +1,756 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+)
+
+// This file decodes the xz format, which Go's standard library lacks. See
+// https://tukaani.org/xz/xz-file-format.txt. It supports the LZMA2 filter,
+// which xz uses unless told otherwise, and not the delta or branch filters.
+
+// Errors reading xz data.
+var (
+	errXZData     = errors.New("xz: invalid data")
+	errXZChecksum = errors.New("xz: checksum mismatch")
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// The states of an xzReader between calls to step.
+const (
+	xzStreamHeader = iota
+	xzBlockHeader
+	xzBlockData
+	xzStreamPadding
+)
+
+// xzReader decompresses xz data from r.
+type xzReader struct {
+	r         *bufio.Reader
+	n         int64       // Bytes read from r since the last reset of n
+	crc       hash.Hash32 // If not nil, receives every byte read from r
+	state     int
+	flags     [2]byte // The current stream's flags
+	checkType byte
+	check     hash.Hash // The current block's check, or nil
+	records   []xzRecord
+	headerLen int64
+	// The sizes of the current block's data the block header gives, or -1.
+	compressed, uncompressed int64
+	size                     int64 // Uncompressed bytes in the block so far
+	lz                       lzma2Decoder
+	out                      []byte // Decompressed data not yet returned by Read
+	err                      error
+}
+
+// xzRecord is the sizes of a block, as the index lists them.
+type xzRecord struct {
+	unpadded, uncompressed int64
+}
+
+// newXZReader returns a reader decompressing the xz data r starts with.
+func newXZReader(r *bufio.Reader) *xzReader {
+	return &xzReader{r: r}
+}
+
+func (x *xzReader) Read(p []byte) (int, error) {
+	for len(x.out) == 0 {
+		if x.err != nil {
+			return 0, x.err
+		}
+		x.err = x.step()
+	}
+	n := copy(p, x.out)
+	x.out = x.out[n:]
+	return n, nil
+}
+
+// ReadByte reads a byte from x.r, counting it and adding it to x.crc.
+func (x *xzReader) ReadByte() (byte, error) {
+	b, err := x.r.ReadByte()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return 0, err
+	}
+	x.n++
+	if x.crc != nil {
+		x.crc.Write([]byte{b})
+	}
+	return b, nil
+}
+
+// readFull fills buf from x.r, counting its bytes and adding them to x.crc.
+func (x *xzReader) readFull(buf []byte) error {
+	n, err := io.ReadFull(x.r, buf)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	x.n += int64(n)
+	if x.crc != nil {
+		x.crc.Write(buf[:n])
+	}
+	return err
+}
+
+// step reads the next part of the data, leaving any decompressed data in
+// x.out. It returns io.EOF after the last stream.
+func (x *xzReader) step() error {
+	switch x.state {
+	case xzStreamHeader:
+		return x.readStreamHeader()
+	case xzBlockHeader:
+		x.n, x.crc = 0, crc32.NewIEEE()
+		b, err := x.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b == 0 {
+			return x.readIndex()
+		}
+		return x.readBlockHeader(b)
+	case xzBlockData:
+		end, err := x.lz.decodeChunk(x)
+		if err != nil {
+			return err
+		}
+		if end {
+			return x.finishBlock()
+		}
+		x.size += int64(len(x.out))
+		if x.uncompressed >= 0 && x.size > x.uncompressed {
+			return errXZData
+		}
+		if x.check != nil {
+			x.check.Write(x.out)
+		}
+		return nil
+	}
+	// Stream padding is zeros in multiples of four bytes, which another
+	// stream or the end of the data follows.
+	for {
+		b, err := x.r.Peek(4)
+		if len(b) == 0 && err == io.EOF {
+			return io.EOF
+		} else if len(b) < 4 {
+			return io.ErrUnexpectedEOF
+		}
+		if !bytes.Equal(b, []byte{0, 0, 0, 0}) {
+			x.state = xzStreamHeader
+			return nil
+		}
+		x.r.Discard(4)
+	}
+}
+
+// readStreamHeader reads the 12-byte header starting a stream.
+func (x *xzReader) readStreamHeader() error {
+	var header [12]byte
+	x.crc = nil
+	if err := x.readFull(header[:]); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:6], xzMagic) ||
+		crc32.ChecksumIEEE(header[6:8]) != binary.LittleEndian.Uint32(header[8:]) {
+		return errXZData
+	}
+	if header[6] != 0 || header[7] > 0xf {
+		return fmt.Errorf("xz: unsupported stream flags %#x", header[6:8])
+	}
+	copy(x.flags[:], header[6:8])
+	x.checkType = header[7]
+	x.records = x.records[:0]
+	x.state = xzBlockHeader
+	return nil
+}
+
+// readBlockHeader reads a block header, whose first byte is b.
+func (x *xzReader) readBlockHeader(b byte) error {
+	header := make([]byte, (int(b)+1)*4)
+	header[0] = b
+	x.crc = nil
+	if err := x.readFull(header[1:]); err != nil {
+		return err
+	}
+	crc := binary.LittleEndian.Uint32(header[len(header)-4:])
+	header = header[:len(header)-4]
+	if crc32.ChecksumIEEE(header) != crc {
+		return errXZData
+	}
+	flags := header[1]
+	if flags&0x3c != 0 {
+		return fmt.Errorf("xz: unsupported block flags %#x", flags)
+	}
+	r := bytes.NewReader(header[2:])
+	x.compressed, x.uncompressed = -1, -1
+	var err error
+	if flags&0x40 != 0 {
+		if x.compressed, err = readXZVarint(r); err != nil {
+			return err
+		}
+	}
+	if flags&0x80 != 0 {
+		if x.uncompressed, err = readXZVarint(r); err != nil {
+			return err
+		}
+	}
+	id, err := readXZVarint(r)
+	if err != nil {
+		return err
+	}
+	if flags&3 != 0 || id != 0x21 {
+		return fmt.Errorf("xz: unsupported filter %#x; only LZMA2 is supported", id)
+	}
+	if size, err := readXZVarint(r); err != nil || size != 1 {
+		return errXZData
+	}
+	props, _ := r.ReadByte()
+	if props > 40 {
+		return errXZData
+	}
+	for r.Len() > 0 {
+		if b, _ := r.ReadByte(); b != 0 {
+			return errXZData
+		}
+	}
+	dictSize := int64(0xffffffff)
+	if props < 40 {
+		dictSize = int64(2|props&1) << (props/2 + 11)
+	}
+	x.lz.reset(dictSize)
+	switch x.checkType {
+	case 0x01:
+		x.check = crc32.NewIEEE()
+	case 0x04:
+		x.check = crc64.New(crc64Table)
+	case 0x0a:
+		x.check = sha256.New()
+	default:
+		x.check = nil
+	}
+	x.headerLen, x.n, x.size = int64(len(header)+4), 0, 0
+	x.state = xzBlockData
+	return nil
+}
+
+// finishBlock reads the block padding and check after a block's data.
+func (x *xzReader) finishBlock() error {
+	compressed := x.n
+	if x.compressed >= 0 && x.compressed != compressed ||
+		x.uncompressed >= 0 && x.uncompressed != x.size {
+		return errXZData
+	}
+	if err := x.readPadding(x.headerLen + compressed); err != nil {
+		return err
+	}
+	// Check sizes are 0, 4, 8, 16, 32, and 64 bytes for three types each.
+	checkLen := 0
+	if x.checkType > 0 {
+		checkLen = 4 << ((x.checkType - 1) / 3)
+	}
+	check := make([]byte, checkLen)
+	if err := x.readFull(check); err != nil {
+		return err
+	}
+	if x.check != nil {
+		sum := x.check.Sum(nil)
+		if x.checkType != 0x0a { // xz stores CRCs in little-endian order.
+			for i, j := 0, len(sum)-1; i < j; i, j = i+1, j-1 {
+				sum[i], sum[j] = sum[j], sum[i]
+			}
+		}
+		if !bytes.Equal(sum, check) {
+			return errXZChecksum
+		}
+	}
+	x.records = append(x.records,
+		xzRecord{x.headerLen + compressed + int64(checkLen), x.size})
+	x.state = xzBlockHeader
+	return nil
+}
+
+// readPadding reads the zeros padding n bytes to a multiple of four.
+func (x *xzReader) readPadding(n int64) error {
+	for ; n%4 != 0; n++ {
+		if b, err := x.ReadByte(); err != nil {
+			return err
+		} else if b != 0 {
+			return errXZData
+		}
+	}
+	return nil
+}
+
+// readIndex reads the index, whose first byte step has read, and the stream
+// footer, checking them against the blocks in the stream.
+func (x *xzReader) readIndex() error {
+	count, err := readXZVarint(x)
+	if err != nil {
+		return err
+	}
+	if count != int64(len(x.records)) {
+		return errXZData
+	}
+	for _, record := range x.records {
+		var r xzRecord
+		if r.unpadded, err = readXZVarint(x); err != nil {
+			return err
+		}
+		if r.uncompressed, err = readXZVarint(x); err != nil {
+			return err
+		}
+		if r != record {
+			return errXZData
+		}
+	}
+	if err := x.readPadding(x.n); err != nil {
+		return err
+	}
+	crc := x.crc.Sum32()
+	indexLen := x.n + 4
+	var footer [16]byte
+	x.crc = nil
+	if err := x.readFull(footer[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(footer[:4]) != crc ||
+		crc32.ChecksumIEEE(footer[8:14]) != binary.LittleEndian.Uint32(footer[4:8]) ||
+		(int64(binary.LittleEndian.Uint32(footer[8:12]))+1)*4 != indexLen ||
+		!bytes.Equal(footer[12:14], x.flags[:]) || string(footer[14:]) != "YZ" {
+		return errXZData
+	}
+	x.state = xzStreamPadding
+	return nil
+}
+
+// readXZVarint reads an integer of up to 63 bits in xz's variable-length
+// encoding of seven bits per byte.
+func readXZVarint(r io.ByteReader) (int64, error) {
+	var n int64
+	for i := uint(0); i < 9; i++ {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			return 0, errXZData
+		} else if err != nil {
+			return 0, err
+		}
+		n |= int64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			if b == 0 && i > 0 {
+				return 0, errXZData
+			}
+			return n, nil
+		}
+	}
+	return 0, errXZData
+}
+
+// lzma2Decoder decodes the LZMA2 chunks making up a block's data.
+type lzma2Decoder struct {
+	dictSize int64
+	history  []byte // Decompressed data, the last dictSize bytes of which matter
+	pos      uint64 // Bytes decompressed since the last dictionary reset
+	packed   []byte // The current chunk's compressed data
+	rc       rangeDecoder
+	lzma     lzmaDecoder
+	// Whether the next chunk must reset the dictionary or set the properties.
+	needDictReset, needProps bool
+}
+
+// reset prepares d to decode a block with a dictionary of dictSize bytes.
+func (d *lzma2Decoder) reset(dictSize int64) {
+	d.dictSize = dictSize
+	d.history = d.history[:0]
+	d.pos = 0
+	d.needDictReset, d.needProps = true, true
+}
+
+// decodeChunk decodes the next chunk and sets x.out to the decompressed data.
+// It returns true if the chunk ends the block's data.
+func (d *lzma2Decoder) decodeChunk(x *xzReader) (end bool, err error) {
+	control, err := x.ReadByte()
+	if err != nil {
+		return false, err
+	} else if control == 0 {
+		return true, nil
+	}
+	var sizes [5]byte
+	switch {
+	case control == 1:
+		d.needDictReset = false
+		d.history, d.pos = d.history[:0], 0
+	case control == 2 && !d.needDictReset:
+	case control >= 0xe0:
+		d.needDictReset = false
+		d.history, d.pos = d.history[:0], 0
+	case control >= 0x80 && !d.needDictReset:
+	default:
+		return false, errXZData
+	}
+	// Keep only the last dictSize bytes once history is twice as long.
+	if int64(len(d.history)) > 2*d.dictSize {
+		n := copy(d.history, d.history[int64(len(d.history))-d.dictSize:])
+		d.history = d.history[:n]
+	}
+	start := len(d.history)
+	if control < 0x80 { // An uncompressed chunk
+		if err := x.readFull(sizes[:2]); err != nil {
+			return false, err
+		}
+		size := int(binary.BigEndian.Uint16(sizes[:2])) + 1
+		d.history = append(d.history, make([]byte, size)...)
+		if err := x.readFull(d.history[start:]); err != nil {
+			return false, err
+		}
+		d.pos += uint64(size)
+		x.out = d.history[start:]
+		return false, nil
+	}
+	reset := control >> 5 & 3
+	n := 4
+	if reset >= 2 {
+		n = 5
+	}
+	if err := x.readFull(sizes[:n]); err != nil {
+		return false, err
+	}
+	size := int(control&0x1f)<<16 + int(binary.BigEndian.Uint16(sizes[:2])) + 1
+	packed := int(binary.BigEndian.Uint16(sizes[2:4])) + 1
+	if reset >= 2 {
+		if err := d.lzma.setProps(sizes[4]); err != nil {
+			return false, err
+		}
+		d.needProps = false
+	} else if d.needProps {
+		return false, errXZData
+	}
+	if reset >= 1 {
+		d.lzma.reset()
+	}
+	if cap(d.packed) < packed {
+		d.packed = make([]byte, packed)
+	}
+	d.packed = d.packed[:packed]
+	if err := x.readFull(d.packed); err != nil {
+		return false, err
+	}
+	if err := d.rc.init(d.packed); err != nil {
+		return false, err
+	}
+	d.history = append(d.history, make([]byte, size)...)
+	if err := d.lzma.decode(&d.rc, d, start); err != nil {
+		return false, err
+	}
+	if !d.rc.finished() {
+		return false, errXZData
+	}
+	x.out = d.history[start:]
+	return false, nil
+}
+
+// rangeDecoder decodes the bits of an LZMA chunk.
+type rangeDecoder struct {
+	in         []byte
+	rng, code  uint32
+	overflowed bool // Whether decoding read past in
+}
+
+func (rc *rangeDecoder) init(in []byte) error {
+	if len(in) < 5 || in[0] != 0 {
+		return errXZData
+	}
+	rc.rng, rc.code = 0xffffffff, binary.BigEndian.Uint32(in[1:5])
+	rc.in, rc.overflowed = in[5:], false
+	return nil
+}
+
+// finished reports whether rc decoded all its input, as it must have at the
+// end of a chunk. It may read the last byte of input.
+func (rc *rangeDecoder) finished() bool {
+	rc.normalize()
+	return !rc.overflowed && len(rc.in) == 0 && rc.code == 0
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < 1<<24 {
+		var b byte
+		if len(rc.in) > 0 {
+			b, rc.in = rc.in[0], rc.in[1:]
+		} else {
+			rc.overflowed = true
+		}
+		rc.rng <<= 8
+		rc.code = rc.code<<8 | uint32(b)
+	}
+}
+
+// bit decodes a bit with the probability *prob of being zero, in units of
+// 1/2048, and adapts *prob to the bit.
+func (rc *rangeDecoder) bit(prob *uint16) uint32 {
+	rc.normalize()
+	bound := (rc.rng >> 11) * uint32(*prob)
+	if rc.code < bound {
+		rc.rng = bound
+		*prob += (2048 - *prob) >> 5
+		return 0
+	}
+	rc.rng -= bound
+	rc.code -= bound
+	*prob -= *prob >> 5
+	return 1
+}
+
+// direct decodes n bits with equal probabilities.
+func (rc *rangeDecoder) direct(n uint32) uint32 {
+	var result uint32
+	for ; n > 0; n-- {
+		rc.normalize()
+		rc.rng >>= 1
+		result <<= 1
+		if rc.code >= rc.rng {
+			rc.code -= rc.rng
+			result |= 1
+		}
+	}
+	return result
+}
+
+// tree decodes n bits, most significant first, with a binary tree of
+// probabilities.
+func (rc *rangeDecoder) tree(probs []uint16, n uint32) uint32 {
+	m := uint32(1)
+	for i := uint32(0); i < n; i++ {
+		m = m<<1 | rc.bit(&probs[m])
+	}
+	return m - 1<<n
+}
+
+// reverseTree is like tree, but decodes the least significant bit first.
+func (rc *rangeDecoder) reverseTree(probs []uint16, n uint32) uint32 {
+	m, result := uint32(1), uint32(0)
+	for i := uint32(0); i < n; i++ {
+		b := rc.bit(&probs[m])
+		m = m<<1 | b
+		result |= b << i
+	}
+	return result
+}
+
+// lzmaLenDecoder decodes match lengths, which are from 2 to 273.
+type lzmaLenDecoder struct {
+	choice, choice2 uint16
+	low, mid        [16][8]uint16
+	high            [256]uint16
+}
+
+func (l *lzmaLenDecoder) reset() {
+	l.choice, l.choice2 = 1024, 1024
+	for i := range l.low {
+		fillProbs(l.low[i][:])
+		fillProbs(l.mid[i][:])
+	}
+	fillProbs(l.high[:])
+}
+
+func (l *lzmaLenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.bit(&l.choice) == 0 {
+		return 2 + rc.tree(l.low[posState][:], 3)
+	}
+	if rc.bit(&l.choice2) == 0 {
+		return 10 + rc.tree(l.mid[posState][:], 3)
+	}
+	return 18 + rc.tree(l.high[:], 8)
+}
+
+// lzmaDecoder is the state of LZMA decoding that LZMA2 chunks share.
+type lzmaDecoder struct {
+	lc, lp, pb uint32
+	state      uint32
+	rep        [4]uint32 // The distances of the last four matches, minus one
+	isMatch    [12 << 4]uint16
+	isRep      [12]uint16
+	isRepG0    [12]uint16
+	isRepG1    [12]uint16
+	isRepG2    [12]uint16
+	isRep0Long [12 << 4]uint16
+	posSlot    [4][64]uint16
+	specPos    [115]uint16 // specPos[0] is unused, as in probability trees
+	align      [16]uint16
+	matchLen   lzmaLenDecoder
+	repLen     lzmaLenDecoder
+	literal    []uint16
+}
+
+// setProps sets the numbers of literal context, literal position, and
+// position bits from an LZMA2 properties byte.
+func (l *lzmaDecoder) setProps(props byte) error {
+	if props >= 9*5*5 {
+		return errXZData
+	}
+	l.lc, l.lp, l.pb = uint32(props%9), uint32(props/9%5), uint32(props/45)
+	if l.lc+l.lp > 4 {
+		return errXZData
+	}
+	l.literal = make([]uint16, 0x300<<(l.lc+l.lp))
+	return nil
+}
+
+// reset sets every probability to one half and forgets previous matches.
+func (l *lzmaDecoder) reset() {
+	l.state, l.rep = 0, [4]uint32{}
+	fillProbs(l.isMatch[:])
+	fillProbs(l.isRep[:])
+	fillProbs(l.isRepG0[:])
+	fillProbs(l.isRepG1[:])
+	fillProbs(l.isRepG2[:])
+	fillProbs(l.isRep0Long[:])
+	for i := range l.posSlot {
+		fillProbs(l.posSlot[i][:])
+	}
+	fillProbs(l.specPos[:])
+	fillProbs(l.align[:])
+	l.matchLen.reset()
+	l.repLen.reset()
+	fillProbs(l.literal)
+}
+
+func fillProbs(probs []uint16) {
+	for i := range probs {
+		probs[i] = 1024
+	}
+}
+
+// decode fills d.history[w:] from rc, where d.history[:w] is the data
+// decompressed so far.
+func (l *lzmaDecoder) decode(rc *rangeDecoder, d *lzma2Decoder, w int) error {
+	h := d.history
+	for w < len(h) {
+		posState := uint32(d.pos) & (1<<l.pb - 1)
+		if rc.bit(&l.isMatch[l.state<<4|posState]) == 0 {
+			var prev uint32
+			if w > 0 {
+				prev = uint32(h[w-1])
+			}
+			i := (uint32(d.pos)&(1<<l.lp-1))<<l.lc | prev>>(8-l.lc)
+			probs := l.literal[0x300*i : 0x300*(i+1)]
+			symbol := uint32(1)
+			if l.state >= 7 {
+				if int64(l.rep[0]) >= int64(w) {
+					return errXZData
+				}
+				match := uint32(h[w-int(l.rep[0])-1])
+				for symbol < 0x100 {
+					matchBit := match >> 7 & 1
+					match <<= 1
+					b := rc.bit(&probs[(1+matchBit)<<8|symbol])
+					symbol = symbol<<1 | b
+					if b != matchBit {
+						break
+					}
+				}
+			}
+			for symbol < 0x100 {
+				symbol = symbol<<1 | rc.bit(&probs[symbol])
+			}
+			h[w] = byte(symbol)
+			w++
+			d.pos++
+			switch {
+			case l.state < 4:
+				l.state = 0
+			case l.state < 10:
+				l.state -= 3
+			default:
+				l.state -= 6
+			}
+			continue
+		}
+		var length uint32
+		if rc.bit(&l.isRep[l.state]) == 0 {
+			length = l.matchLen.decode(rc, posState)
+			l.rep[3], l.rep[2], l.rep[1] = l.rep[2], l.rep[1], l.rep[0]
+			l.rep[0] = l.distance(rc, length)
+			if l.state < 7 {
+				l.state = 7
+			} else {
+				l.state = 10
+			}
+		} else {
+			if rc.bit(&l.isRepG0[l.state]) == 0 {
+				if rc.bit(&l.isRep0Long[l.state<<4|posState]) == 0 {
+					if l.state < 7 {
+						l.state = 9
+					} else {
+						l.state = 11
+					}
+					length = 1
+				}
+			} else {
+				var dist uint32
+				if rc.bit(&l.isRepG1[l.state]) == 0 {
+					dist = l.rep[1]
+				} else {
+					if rc.bit(&l.isRepG2[l.state]) == 0 {
+						dist = l.rep[2]
+					} else {
+						dist = l.rep[3]
+						l.rep[3] = l.rep[2]
+					}
+					l.rep[2] = l.rep[1]
+				}
+				l.rep[1] = l.rep[0]
+				l.rep[0] = dist
+			}
+			if length == 0 {
+				length = l.repLen.decode(rc, posState)
+				if l.state < 7 {
+					l.state = 8
+				} else {
+					l.state = 11
+				}
+			}
+		}
+		// Matches never cross chunks, so this also rules out the end marker,
+		// which LZMA2 forbids.
+		dist := int64(l.rep[0])
+		if dist >= int64(w) || dist >= d.dictSize || int(length) > len(h)-w {
+			return errXZData
+		}
+		for i := 0; i < int(length); i++ {
+			h[w] = h[w-int(dist)-1]
+			w++
+		}
+		d.pos += uint64(length)
+	}
+	return nil
+}
+
+// distance decodes the distance, minus one, of a match of the given length.
+func (l *lzmaDecoder) distance(rc *rangeDecoder, length uint32) uint32 {
+	lenState := length - 2
+	if lenState > 3 {
+		lenState = 3
+	}
+	slot := rc.tree(l.posSlot[lenState][:], 6)
+	if slot < 4 {
+		return slot
+	}
+	n := slot>>1 - 1
+	dist := (2 | slot&1) << n
+	if slot < 14 {
+		return dist + rc.reverseTree(l.specPos[dist-slot:], n)
+	}
+	dist += rc.direct(n-4) << 4
+	return dist + rc.reverseTree(l.align[:], 4)
+}
@@ -0,0 +1,98 @@
+// Copyright William Schwartz 2014. See the LICENSE file for more information.
+
+package pigosat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// xzCNF is "p cnf 2 2\n1 -2 0\n2 0\n" compressed with xz, which stores it
+// uncompressed in an LZMA2 chunk because it is so short.
+var xzCNF = []byte{
+	0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00, 0x00, 0x04, 0xe6, 0xd6, 0xb4, 0x46,
+	0x04, 0xc0, 0x19, 0x15, 0x21, 0x01, 0x16, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x09, 0xe3, 0x90, 0xb5, 0x01, 0x00, 0x14, 0x70,
+	0x20, 0x63, 0x6e, 0x66, 0x20, 0x32, 0x20, 0x32, 0x0a, 0x31, 0x20, 0x2d,
+	0x32, 0x20, 0x30, 0x0a, 0x32, 0x20, 0x30, 0x0a, 0x00, 0x00, 0x00, 0x00,
+	0x55, 0xac, 0x00, 0xbc, 0x90, 0x02, 0xf1, 0x95, 0x00, 0x01, 0x35, 0x15,
+	0x76, 0x93, 0x6a, 0xef, 0x1f, 0xb6, 0xf3, 0x7d, 0x01, 0x00, 0x00, 0x00,
+	0x00, 0x04, 0x59, 0x5a,
+}
+
+// xzLZMA is "p cnf 3 100\n" followed by 50 copies of "1 -2 0\n2 3 0\n"
+// compressed with xz -C crc32, which stores it in a compressed LZMA2 chunk.
+var xzLZMA = []byte{
+	0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00, 0x00, 0x01, 0x69, 0x22, 0xde, 0x36,
+	0x04, 0xc0, 0x28, 0x96, 0x05, 0x21, 0x01, 0x16, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x28, 0xc1, 0xea, 0x19, 0xe0, 0x02, 0x95, 0x00,
+	0x20, 0x5d, 0x00, 0x38, 0x08, 0x08, 0x67, 0x23, 0xbd, 0x95, 0x96, 0x29,
+	0xc2, 0xab, 0x78, 0x2c, 0x94, 0x27, 0xab, 0xbc, 0xe0, 0xfe, 0x1e, 0x09,
+	0xe6, 0x13, 0x9b, 0x7e, 0x21, 0xd3, 0xe3, 0x5e, 0x15, 0x40, 0x00, 0x00,
+	0x53, 0x8e, 0xd4, 0x77, 0x00, 0x01, 0x40, 0x96, 0x05, 0x00, 0x00, 0x00,
+	0x33, 0xfc, 0x95, 0xa5, 0x3e, 0x30, 0x0d, 0x8b, 0x02, 0x00, 0x00, 0x00,
+	0x00, 0x01, 0x59, 0x5a,
+}
+
+func TestXZ(t *testing.T) {
+	expected := "p cnf 3 100\n" + strings.Repeat("1 -2 0\n2 3 0\n", 50)
+	r, compression, err := Decompress(bytes.NewReader(xzLZMA))
+	if err != nil || compression != XZ {
+		t.Fatalf("Expected XZ, got %v, %v", compression, err)
+	}
+	if data, err := ioutil.ReadAll(r); err != nil || string(data) != expected {
+		t.Errorf("Expected %q, got %q, %v", expected, data, err)
+	}
+	formula, variables, err := ReadDIMACS(bytes.NewReader(xzLZMA))
+	if err != nil || variables != 3 || len(formula) != 100 ||
+		!reflect.DeepEqual(formula[98:], Formula{{1, -2}, {2, 3}}) {
+		t.Errorf("ReadDIMACS: got %v, %d, %v", formula[98:], variables, err)
+	}
+
+	// Streams may be concatenated with zeros padding them between.
+	var streams bytes.Buffer
+	streams.Write(xzCNF)
+	streams.Write(make([]byte, 8))
+	streams.Write(xzLZMA)
+	streams.Write(make([]byte, 4))
+	r, _, _ = Decompress(&streams)
+	data, err := ioutil.ReadAll(r)
+	if err != nil || string(data) != "p cnf 2 2\n1 -2 0\n2 0\n"+expected {
+		t.Errorf("Concatenated streams: got %q, %v", data, err)
+	}
+
+	// Checksums cover every byte after the magic number, so every change is
+	// an error.
+	for i := len(xzMagic); i < len(xzLZMA); i++ {
+		bad := append([]byte(nil), xzLZMA...)
+		bad[i] ^= 0x10
+		r, _, err := Decompress(bytes.NewReader(bad))
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+		}
+		if err == nil {
+			t.Errorf("Expected an error changing byte %d", i)
+		}
+	}
+	for i := len(xzMagic); i < len(xzLZMA); i++ {
+		r, _, _ := Decompress(bytes.NewReader(xzLZMA[:i]))
+		if _, err := ioutil.ReadAll(r); err == nil {
+			t.Errorf("Expected an error truncating to %d bytes", i)
+		}
+	}
+
+	// The delta filter is unsupported. This is the header and block header of
+	// xz --delta=dist=1 --lzma2 output.
+	delta := []byte{
+		0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00, 0x00, 0x04, 0xe6, 0xd6, 0xb4, 0x46,
+		0x04, 0xc1, 0x2c, 0x96, 0x05, 0x03, 0x01, 0x00, 0x21, 0x01, 0x16, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0xcc, 0x10, 0xb3, 0xfe,
+	}
+	if _, _, err := ReadDIMACS(bytes.NewReader(delta)); err == nil ||
+		!strings.Contains(err.Error(), "filter") {
+		t.Errorf("Expected an unsupported filter error, got %v", err)
+	}
+}